@@ -3,6 +3,7 @@ package main
 import (
 	"crap/internal/app"
 	_ "crap/docs"
+	_ "time/tzdata"
 )
 
 //	@title			На жри уебок доки свои не подавись (недоделанная кстати хихиххихихих))))))
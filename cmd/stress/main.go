@@ -0,0 +1,180 @@
+// Command stress registers a batch of throwaway users and makes them join and leave
+// one event at the same time. It is run against a live server to check that
+// concurrent requests do not break the pool or the event's member count.
+// A full event puts the extra users on its waitlist, the join still answers 200.
+// Capacity and the waitlist order are asserted by TestJoinUnderLoad in
+// internal/domain/services, which needs the integration build tag and a database.
+//
+//	go run ./cmd/stress -event <event id> -users 50 -rounds 20
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type client struct {
+	id   string
+	http *http.Client
+}
+
+type stats struct {
+	mu     sync.Mutex
+	codes  map[string]int
+	errors map[string]int
+}
+
+func (s *stats) add(op string, code int, body []byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.errors[op+": "+err.Error()]++
+		return
+	}
+	s.codes[op+" "+strconv.Itoa(code)]++
+	if code >= http.StatusInternalServerError {
+		s.errors[op+": "+string(bytes.TrimSpace(body))]++
+	}
+}
+
+func main() {
+	addr := flag.String("addr", "http://localhost:1111/api", "api base url")
+	event := flag.String("event", "", "id of the event to join and leave")
+	users := flag.Int("users", 20, "number of concurrent users")
+	rounds := flag.Int("rounds", 20, "join/unjoin rounds per user")
+	password := flag.String("password", "stress-password", "password of the generated users")
+	flag.Parse()
+	if *event == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	prefix := "stress-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	clients := make([]*client, 0, *users)
+	for i := 0; i < *users; i++ {
+		c, err := signUp(*addr, fmt.Sprintf("%s-%d", prefix, i), *password)
+		if err != nil {
+			log.Fatalf("failed to prepare user %d: %v", i, err)
+		}
+		clients = append(clients, c)
+	}
+	log.Printf("prepared %d users, starting %d rounds each", len(clients), *rounds)
+
+	s := &stats{codes: map[string]int{}, errors: map[string]int{}}
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	begin := time.Now()
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *client) {
+			defer wg.Done()
+			<-start
+			body := map[string]string{"user-id": c.id, "event-id": *event}
+			for r := 0; r < *rounds; r++ {
+				code, resp, err := c.do(http.MethodPatch, *addr+"/events/join", body)
+				s.add("join", code, resp, err)
+				code, resp, err = c.do(http.MethodPatch, *addr+"/events/unjoin", body)
+				s.add("unjoin", code, resp, err)
+			}
+		}(c)
+	}
+	close(start)
+	wg.Wait()
+	elapsed := time.Since(begin)
+
+	total := 0
+	for _, n := range s.codes {
+		total += n
+	}
+	fmt.Printf("%d requests in %v (%.1f req/s)\n", total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds())
+	printCounts("responses", s.codes)
+	printCounts("errors", s.errors)
+	if len(s.errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+func signUp(addr, login, password string) (*client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	c := &client{http: &http.Client{Jar: jar, Timeout: 10 * time.Second}}
+	code, body, err := c.do(http.MethodPost, addr+"/auth/register", map[string]string{
+		"login":    login,
+		"telegram": login,
+		"password": password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("register returned %d: %s", code, body)
+	}
+	registered := struct {
+		Id string `json:"id"`
+	}{}
+	if err := json.Unmarshal(body, &registered); err != nil {
+		return nil, err
+	}
+	c.id = registered.Id
+	code, body, err = c.do(http.MethodPost, addr+"/auth/login", map[string]string{
+		"login":    login,
+		"password": password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("login returned %d: %s", code, body)
+	}
+	return c, nil
+}
+
+func (c *client) do(method, url string, payload any) (int, []byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+func printCounts(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Println(title + ":")
+	for _, k := range keys {
+		fmt.Printf("  %6d  %s\n", counts[k], k)
+	}
+}
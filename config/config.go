@@ -4,6 +4,7 @@ import (
 //	"fmt"
 	"github.com/spf13/viper"
 	"log"
+	"time"
 	//"strings"
 	//"github.com/caarlos0/env/v11"
 )
@@ -15,11 +16,19 @@ type Config struct{
 	Redis RedisCfg
 	Bot BotCfg
 	Auth AuthCfg
+	Event EventCfg
+	Lfg LfgCfg
+	Outbox OutboxCfg
+	Smtp SmtpCfg
+	Webhook WebhookCfg
 }
 
 type AppCfg struct{
 	Name string `env:"APP_NAME,required"`
 	Version string `env:"APP_VERSION,required"`
+	// Url is the public address of the site, event links in notifications
+	// start with it.
+	Url string `mapstructure:"url" env:"APP_URL"`
 }
 
 type ServerCfg struct{
@@ -33,6 +42,12 @@ type PostgresCfg struct{
 	Database string `env:"POSTGRES_DB,required"`
 	User string `env:"POSTGRES_USER,required"`
 	Password string `env:"POSTGRES_PASSWORD,required"`
+	MaxConns int32 `mapstructure:"max_conns" env:"PG_MAX_CONNS"`
+	MinConns int32 `mapstructure:"min_conns" env:"PG_MIN_CONNS"`
+	MaxConnLifetime time.Duration `mapstructure:"max_conn_lifetime" env:"PG_MAX_CONN_LIFETIME"`
+	MaxConnIdleTime time.Duration `mapstructure:"max_conn_idle_time" env:"PG_MAX_CONN_IDLE_TIME"`
+	HealthCheckPeriod time.Duration `mapstructure:"health_check_period" env:"PG_HEALTH_CHECK_PERIOD"`
+	StatementTimeout time.Duration `mapstructure:"statement_timeout" env:"PG_STATEMENT_TIMEOUT"`
 }
 
 type RedisCfg struct{
@@ -43,12 +58,52 @@ type RedisCfg struct{
 
 type BotCfg struct{
 	Token string `env:"TG_BOT_TOKEN,required"`
+	// Name is the username of the bot, the account links point to it.
+	Name string `mapstructure:"name" env:"TG_BOT_NAME"`
+	// LinkTTL is how long an account link stays valid.
+	LinkTTL time.Duration `mapstructure:"link_ttl" env:"TG_LINK_TTL"`
+}
+
+type SmtpCfg struct{
+	Host string `mapstructure:"host" env:"SMTP_HOST"`
+	Port string `mapstructure:"port" env:"SMTP_PORT"`
+	User string `mapstructure:"user" env:"SMTP_USER"`
+	Password string `mapstructure:"password" env:"SMTP_PASSWORD"`
+	From string `mapstructure:"from" env:"SMTP_FROM"`
+}
+
+type WebhookCfg struct{
+	Timeout time.Duration `mapstructure:"timeout" env:"WEBHOOK_TIMEOUT"`
 }
 
 type AuthCfg struct{
 	Secret string `env:"SECRET,required"`
+	Admins []string `mapstructure:"admins" env:"ADMINS"`
 }
 
+type EventCfg struct{
+	MinLead time.Duration `mapstructure:"min_lead" env:"EVENT_MIN_LEAD"`
+	MaxHorizon time.Duration `mapstructure:"max_horizon" env:"EVENT_MAX_HORIZON"`
+	StartingLead time.Duration `mapstructure:"starting_lead" env:"EVENT_STARTING_LEAD"`
+	Duration time.Duration `mapstructure:"duration" env:"EVENT_DURATION"`
+	DefaultReminder time.Duration `mapstructure:"default_reminder" env:"EVENT_DEFAULT_REMINDER"`
+	CheckInBefore time.Duration `mapstructure:"check_in_before" env:"EVENT_CHECK_IN_BEFORE"`
+	CheckInAfter time.Duration `mapstructure:"check_in_after" env:"EVENT_CHECK_IN_AFTER"`
+}
+
+type LfgCfg struct{
+	StartDelay time.Duration `mapstructure:"start_delay" env:"LFG_START_DELAY"`
+	TicketTtl time.Duration `mapstructure:"ticket_ttl" env:"LFG_TICKET_TTL"`
+}
+
+type OutboxCfg struct{
+	Interval time.Duration `mapstructure:"interval" env:"OUTBOX_INTERVAL"`
+	BatchSize int `mapstructure:"batch_size" env:"OUTBOX_BATCH_SIZE"`
+	MaxAttempts int `mapstructure:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS"`
+	BaseDelay time.Duration `mapstructure:"base_delay" env:"OUTBOX_BASE_DELAY"`
+	MaxDelay time.Duration `mapstructure:"max_delay" env:"OUTBOX_MAX_DELAY"`
+	Lease time.Duration `mapstructure:"lease" env:"OUTBOX_LEASE"`
+}
 
 // func LoadConfig() (*Config, error) {
 // 	cfg := Config{}
@@ -50,14 +50,15 @@ func Run() {
 		logger.Info("server created succefully")
 	}
 	bcfg := bootstrap.NewBootstrapConfig(app, postgres, redis, logger, validator)
-	bcfg.BootstrapHandlers(stop, cfg)
 	bot,err:=bcfg.BootstrapBot(stop,cfg)
-	sheduler:=bcfg.BootstrapSheduler(stop,bot,cfg)
 	if err!=nil{
 		logger.WithError(err).Info("error start bot")
 	}else{
 		logger.Info("bot started successful")
 	}
+	bcfg.BootstrapHandlers(stop, bot, cfg)
+	sheduler:=bcfg.BootstrapSheduler(stop,cfg)
+	dispatcher:=bcfg.BootstrapDispatcher(stop,bot,cfg)
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -66,15 +67,22 @@ func Run() {
 			logger.WithError(err).Fatal("failed to start server")
 		}
 	}()
+	if bot != nil {
+		wg.Add(1)
+		go func(){
+			defer wg.Done()
+			bot.ListenForUpdates(stop)
+		}()
+	}
 	wg.Add(1)
 	go func(){
 		defer wg.Done()
-		bot.ListenForUpdates(stop)
+		sheduler.SetupSheduler(stop)
 	}()
 	wg.Add(1)
 	go func(){
 		defer wg.Done()
-		sheduler.SetupSheduler(stop)
+		dispatcher.Run(stop)
 	}()
 	<-quit
 	close(quit)
@@ -85,11 +93,8 @@ func Run() {
 	if err := app.ShutdownWithContext(ctx); err != nil {
 		logger.WithError(err).Fatal("server forced to shutdown")
 	}
-	if err := postgres.Close(ctx); err != nil {
-		logger.WithError(err).Fatal("failed to close postgres")
-	} else {
-		logger.Info("close postgres success")
-	}
+	postgres.Close()
+	logger.Info("close postgres success")
 	if redis != nil {
 		if err := redis.Close(); err != nil {
 			logger.WithError(err).Fatal("failed to close redis")
@@ -3,8 +3,10 @@ package bootstrap
 import (
 	"crap/config"
 	"crap/internal/controllers/rest/handlers"
+	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
 	"crap/internal/domain/services"
+	"crap/internal/infrastructure/notify"
 	"crap/internal/routes"
 	"crap/internal/sheduler"
 	"crap/internal/sheduler/bot"
@@ -15,30 +17,61 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 )
 
 type BootstrapConfig struct {
 	App *fiber.App
-	Postgres  *pgx.Conn
+	Postgres  *pgxpool.Pool
 	Redis     *redis.Client
 	Logger    *logrus.Logger
 	Validator *validator.Validate
+	// Lfg is shared by the handlers and the matcher, the in-memory
+	// fallback would otherwise split the queue in two.
+	Lfg       repositories.LfgRepository
+	// Stream is shared for the same reason, the dispatcher publishes
+	// what the handlers stream.
+	Stream    repositories.StreamRepository
+	// Links are issued by the handlers and redeemed by the bot.
+	Links     repositories.LinkRepository
 }
 
-func NewBootstrapConfig(a *fiber.App,p *pgx.Conn, r *redis.Client, l *logrus.Logger, v *validator.Validate) BootstrapConfig{
+func NewBootstrapConfig(a *fiber.App,p *pgxpool.Pool, r *redis.Client, l *logrus.Logger, v *validator.Validate) BootstrapConfig{
 	return BootstrapConfig{
 		App:a,
 		Postgres: p,
 		Redis: r,
 		Logger: l,
 		Validator: v,
+		Lfg: repositories.NewLfgRepository(r),
+		Stream: repositories.NewStreamRepository(r),
+		Links: repositories.NewLinkRepository(r),
 	}
 }
 
-func(bcfg *BootstrapConfig) BootstrapHandlers(stop chan struct{}, cfg *config.Config) {
+// deliverers maps every outbox channel to its sender. Channels that are not
+// configured, e.g. Telegram without a bot, end up in the dead letters and can
+// be replayed later.
+func (bcfg *BootstrapConfig) deliverers(n services.NotificationService, ur repositories.UserRepository, cr repositories.ChannelRepository, dr repositories.DigestRepository, b *bot.Bot, cfg *config.Config) map[string]services.Deliverer {
+	d := map[string]services.Deliverer{
+		entities.ChannelNotification: n,
+		entities.ChannelWebhook:      services.NewChannelDeliverer(entities.ChannelWebhook, notify.NewWebhook(cfg), ur, cr, dr),
+	}
+	if b != nil {
+		d[entities.ChannelTelegram] = services.NewChannelDeliverer(entities.ChannelTelegram, b, ur, cr, dr)
+	}
+	email, err := notify.NewEmail(cfg)
+	if err != nil {
+		bcfg.Logger.WithError(err).Error("email notifications disabled")
+	} else if email != nil {
+		d[entities.ChannelEmail] = services.NewChannelDeliverer(entities.ChannelEmail, email, ur, cr, dr)
+	}
+	return d
+}
+
+func(bcfg *BootstrapConfig) BootstrapHandlers(stop chan struct{}, bot *bot.Bot, cfg *config.Config) {
 
 	transactor := repositories.NewTransactor(bcfg.Postgres)
 
@@ -47,17 +80,32 @@ func(bcfg *BootstrapConfig) BootstrapHandlers(stop chan struct{}, cfg *config.Co
 	eventRepository := repositories.NewEventRepository(bcfg.Postgres, bcfg.Redis)
 	newsRepository := repositories.NewNewsRepository(bcfg.Postgres)
 	commentRepository := repositories.NewCommentRepository(bcfg.Postgres)
-	notificationRepository := repositories.NewNoticeRepository(bcfg.Postgres)
+	notificationRepository := repositories.NewNoticeRepository(bcfg.Postgres, bcfg.Redis)
 	friendshipsRepository:=repositories.NewFriendshipsRepository(bcfg.Postgres)
+	reminderRepository := repositories.NewReminderRepository(bcfg.Postgres)
+	ratingRepository := repositories.NewRatingRepository(bcfg.Postgres)
+	templateRepository := repositories.NewTemplateRepository(bcfg.Postgres)
+	searchRepository := repositories.NewSearchRepository(bcfg.Postgres)
+	outboxRepository := repositories.NewOutboxRepository(bcfg.Postgres)
+	channelRepository := repositories.NewChannelRepository(bcfg.Postgres)
+	digestRepository := repositories.NewDigestRepository(bcfg.Postgres)
 
-	userService := services.NewUserService(userRepository, transactor,cfg)
+	userService := services.NewUserService(userRepository, eventRepository, ratingRepository, reminderRepository, bcfg.Links, transactor,cfg)
 	authService := services.NewAuthService(userRepository,cfg)
 	gameService := services.NewGameService(gameRepository, userRepository, transactor)
-	eventService := services.NewEventService(eventRepository, userRepository, gameRepository, transactor)
+	notificationService := services.NewNotificationService(notificationRepository, eventRepository, userRepository, reminderRepository, outboxRepository, channelRepository, bcfg.Stream, digestRepository, transactor, cfg)
+	eventService := services.NewEventService(eventRepository, userRepository, gameRepository, friendshipsRepository, reminderRepository, templateRepository, notificationService, transactor, cfg)
+	if bot != nil {
+		bot.CheckIns = eventService
+	}
 	newsService := services.NewNewsService(newsRepository, transactor,cfg)
-	notificationService := services.NewNotificationService(notificationRepository, eventRepository, userRepository, transactor)
-	commentService := services.NewCommentService(commentRepository, userRepository, eventRepository, newsRepository, transactor)
-	friendshipsService :=services.NewFriendshipsService(friendshipsRepository,userRepository)
+	commentService := services.NewCommentService(commentRepository, userRepository, eventRepository, newsRepository, notificationService, transactor, cfg)
+	friendshipsService :=services.NewFriendshipsService(friendshipsRepository,userRepository,notificationService,transactor)
+	lfgService := services.NewLfgService(bcfg.Lfg, userRepository, gameRepository, eventService, notificationService, transactor, cfg)
+	templateService := services.NewTemplateService(templateRepository, eventRepository, gameRepository, friendshipsRepository, transactor)
+	searchService := services.NewSearchService(searchRepository)
+	// The handlers only list and replay dead letters, the dispatcher delivers.
+	outboxService := services.NewOutboxService(outboxRepository, nil, transactor, cfg)
 
 	userHandler := handlers.NewUsersHandler(userService, bcfg.Logger, bcfg.Validator)
 	authHander := handlers.NewAuthHandler(authService, bcfg.Logger, bcfg.Validator,cfg)
@@ -67,6 +115,10 @@ func(bcfg *BootstrapConfig) BootstrapHandlers(stop chan struct{}, cfg *config.Co
 	notificationHandler := handlers.NewNotificationsHandler(notificationService, bcfg.Logger, bcfg.Validator)
 	commetHandler :=handlers.NewCommentHandler(commentService,bcfg.Logger,bcfg.Validator)
 	friendshipsHandler:=handlers.NewFriendshipsHandler(friendshipsService,bcfg.Logger,bcfg.Validator)
+	lfgHandler := handlers.NewLfgHandler(lfgService, bcfg.Logger, bcfg.Validator)
+	templateHandler := handlers.NewTemplatesHandler(templateService, bcfg.Logger, bcfg.Validator)
+	searchHandler := handlers.NewSearchHandler(searchService, bcfg.Logger, bcfg.Validator)
+	outboxHandler := handlers.NewOutboxHandler(outboxService, bcfg.Logger, bcfg.Validator)
 
 	routConfig := routes.RoutConfig{
 		App:           bcfg.App,
@@ -78,34 +130,65 @@ func(bcfg *BootstrapConfig) BootstrapHandlers(stop chan struct{}, cfg *config.Co
 		NoticeHandler: &notificationHandler,
 		CommentsHandler: &commetHandler,
 		FriendshipsHandler: &friendshipsHandler,
+		LfgHandler: &lfgHandler,
+		TemplateHandler: &templateHandler,
+		SearchHandler: &searchHandler,
+		OutboxHandler: &outboxHandler,
 	}
 
 	routConfig.Setup()
 }
 
-func(bcfg *BootstrapConfig) BootstrapSheduler(stop chan struct{}, bot *bot.Bot, cfg *config.Config) sheduler.Sheduler{
+func(bcfg *BootstrapConfig) BootstrapSheduler(stop chan struct{}, cfg *config.Config) sheduler.Sheduler{
 	transactor := repositories.NewTransactor(bcfg.Postgres)
 	userRepository := repositories.NewUserRepository(bcfg.Postgres, bcfg.Redis)
-	userService := services.NewUserService(userRepository, transactor,cfg)
+	reminderRepository := repositories.NewReminderRepository(bcfg.Postgres)
+	ratingRepository := repositories.NewRatingRepository(bcfg.Postgres)
 	eventRepository := repositories.NewEventRepository(bcfg.Postgres, bcfg.Redis)
+	userService := services.NewUserService(userRepository, eventRepository, ratingRepository, reminderRepository, bcfg.Links, transactor,cfg)
 	gameRepository := repositories.NewGameRepository(bcfg.Postgres)
-	eventService := services.NewEventService(eventRepository, userRepository, gameRepository, transactor)
-	notificationRepository := repositories.NewNoticeRepository(bcfg.Postgres)
-	notificationService := services.NewNotificationService(notificationRepository, eventRepository, userRepository, transactor)
+	notificationRepository := repositories.NewNoticeRepository(bcfg.Postgres, bcfg.Redis)
+	friendshipsRepository := repositories.NewFriendshipsRepository(bcfg.Postgres)
+	templateRepository := repositories.NewTemplateRepository(bcfg.Postgres)
+	outboxRepository := repositories.NewOutboxRepository(bcfg.Postgres)
+	channelRepository := repositories.NewChannelRepository(bcfg.Postgres)
+	digestRepository := repositories.NewDigestRepository(bcfg.Postgres)
+	notificationService := services.NewNotificationService(notificationRepository, eventRepository, userRepository, reminderRepository, outboxRepository, channelRepository, bcfg.Stream, digestRepository, transactor, cfg)
+	eventService := services.NewEventService(eventRepository, userRepository, gameRepository, friendshipsRepository, reminderRepository, templateRepository, notificationService, transactor, cfg)
+	lfgService := services.NewLfgService(bcfg.Lfg, userRepository, gameRepository, eventService, notificationService, transactor, cfg)
 	sheduler:=sheduler.Sheduler{
 		NotificationService: notificationService,
 		UserService: userService,
 		EventService: eventService,
+		LfgService: lfgService,
 		Logger: bcfg.Logger,
-		Bot: bot,
+		Config: cfg,
 	}
 	return sheduler
 }
 
+func(bcfg *BootstrapConfig) BootstrapDispatcher(stop chan struct{}, bot *bot.Bot, cfg *config.Config) sheduler.Dispatcher{
+	transactor := repositories.NewTransactor(bcfg.Postgres)
+	userRepository := repositories.NewUserRepository(bcfg.Postgres, bcfg.Redis)
+	eventRepository := repositories.NewEventRepository(bcfg.Postgres, bcfg.Redis)
+	reminderRepository := repositories.NewReminderRepository(bcfg.Postgres)
+	notificationRepository := repositories.NewNoticeRepository(bcfg.Postgres, bcfg.Redis)
+	outboxRepository := repositories.NewOutboxRepository(bcfg.Postgres)
+	channelRepository := repositories.NewChannelRepository(bcfg.Postgres)
+	digestRepository := repositories.NewDigestRepository(bcfg.Postgres)
+	notificationService := services.NewNotificationService(notificationRepository, eventRepository, userRepository, reminderRepository, outboxRepository, channelRepository, bcfg.Stream, digestRepository, transactor, cfg)
+	outboxService := services.NewOutboxService(outboxRepository, bcfg.deliverers(notificationService, userRepository, channelRepository, digestRepository, bot, cfg), transactor, cfg)
+	return sheduler.Dispatcher{
+		OutboxService: outboxService,
+		Logger: bcfg.Logger,
+		Config: cfg,
+	}
+}
+
 func(bcfg *BootstrapConfig) BootstrapBot(stop chan struct{}, cfg *config.Config) (*bot.Bot,error){
 	userRepository := repositories.NewUserRepository(bcfg.Postgres, bcfg.Redis)
 	eventRepository := repositories.NewEventRepository(bcfg.Postgres, bcfg.Redis)
-	bot, err := bot.CreateBot(stop,bcfg.Logger, userRepository, eventRepository,cfg.Bot.Token)
+	bot, err := bot.CreateBot(stop,bcfg.Logger, userRepository, eventRepository, bcfg.Links,cfg.Bot.Token)
 	if err != nil {
 		return nil,err
 	}
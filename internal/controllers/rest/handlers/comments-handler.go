@@ -4,6 +4,7 @@ import (
 	"context"
 	"crap/internal/domain/services"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
 	errh "crap/pkg/errors-handlers"
 	"time"
 	"errors"
@@ -74,7 +75,8 @@ func (ch *CommentsHandler) AddComment(c *fiber.Ctx) error{
 // @Param newsId query string false "news ID"
 // @Param amount query int false "amount"
 // @Param page query int false "page"
-// @Success 200 {array} entities.Comment "List of comments"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
+// @Success 200 {object} query.Page[entities.Comment] "Page of comments with cursors"
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -95,6 +97,12 @@ func (ch *CommentsHandler) GetComments(c *fiber.Ctx) error{
 		if errors.Is(err,context.DeadlineExceeded){
 			return errh.RequestTimedOut(eH,err)
 		}
+		if errors.Is(err,query.ErrInvalid){
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error":err.Error(),
+			})
+		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
 			"error":"failed to get comments: " + err.Error(),
@@ -4,9 +4,9 @@ import (
 	"context"
 	"crap/internal/domain/services"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
 	errh "crap/pkg/errors-handlers"
 	"errors"
-	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -68,11 +68,12 @@ func (eh *EventsHandler) CreateEvent(c *fiber.Ctx) error {
 
 // GetEvent godoc
 // @Summary Getting event by ID
-// @Description Returns an event by its id
+// @Description Returns an event by its id with times rendered in the caller's time zone, its member count, free slots per role and the caller's waitlist position. With format=ics the event is returned as a calendar file
 // @Tags events
-// @Produce json
+// @Produce json,text/calendar
 // @Param id path string true "event Id"
-// @Success 200 {object} entities.Event
+// @Param format query string false "ics"
+// @Success 200 {object} dto.EventDetailsResponse
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -82,7 +83,22 @@ func (eh *EventsHandler) GetEvent(c *fiber.Ctx) error {
 	defer cancel()
 	eH := errh.NewErrorHander(c, eh.Logger, "get-event-by-id")
 	id := c.Params("id")
-	event, err := eh.EventService.GetById(ctx, id)
+	if c.Query("format") == "ics" {
+		file, err := eh.EventService.GetCalendar(ctx, id, callerId(c))
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return errh.RequestTimedOut(eH, err)
+			}
+			c.Status(fiber.StatusInternalServerError)
+			return c.JSON(fiber.Map{
+				"error": "failed to get event: " + err.Error(),
+			})
+		}
+		c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+		c.Attachment(id + ".ics")
+		return c.Send(file)
+	}
+	event, err := eh.EventService.GetDetails(ctx, id, callerId(c))
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
@@ -98,12 +114,14 @@ func (eh *EventsHandler) GetEvent(c *fiber.Ctx) error {
 
 // GetEvents godoc
 // @Summary Getting a list of events
-// @Description Returns a list of events with pagination
+// @Description Returns a list of events with pagination, times are rendered in the caller's time zone. Archived events are returned only when requested by state
 // @Tags events
 // @Produce json
+// @Param state query string false "scheduled, starting, in_progress, finished or cancelled"
 // @Param amount query int false "amount"
 // @Param page query int false "page"
-// @Success 200 {array} entities.Event
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
+// @Success 200 {object} query.Page[entities.Event]
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -112,18 +130,24 @@ func (eh *EventsHandler) GetEvents(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
 	defer cancel()
 	eH := errh.NewErrorHander(c, eh.Logger, "get-events")
-	params := dto.PaginationRequest{}
+	params := dto.GetEventsRequest{}
 	if err := c.QueryParser(&params); err != nil {
 		return errh.ParseRequestError(eH, err)
 	}
 	if err := eh.Validator.Struct(params); err != nil {
 		return errh.ValidateRequestError(eH, err)
 	}
-	events, err := eh.EventService.FetchEvents(ctx, params)
+	events, err := eh.EventService.FetchEvents(ctx, params, callerId(c))
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
+		if errors.Is(err, query.ErrInvalid) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
 			"error": "failed to get events: " + err.Error(),
@@ -167,13 +191,13 @@ func (eh *EventsHandler) DeleteEvent(c *fiber.Ctx) error {
 
 // Join godoc
 // @Summary Joining the event
-// @Description Adds a user to the event participants
+// @Description Adds a user to the event participants or to the waitlist when the event or the chosen role slot is full
 // @Tags events
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param request body dto.JoinToEventRequest true "Data for join to event"
-// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Success 200 {object} dto.JoinEventResponse
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -189,7 +213,8 @@ func (eh *EventsHandler) Join(c *fiber.Ctx) error {
 	if err := eh.Validator.Struct(request); err != nil {
 		return errh.ValidateRequestError(eH, err)
 	}
-	if err := eh.EventService.Join(ctx, request); err != nil {
+	response, err := eh.EventService.Join(ctx, request)
+	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
@@ -198,15 +223,51 @@ func (eh *EventsHandler) Join(c *fiber.Ctx) error {
 			"error": "failed to join to event: " + err.Error(),
 		})
 	}
-	eh.Logger.Infof("user %v joined to event %v",request.UserId,request.EventId)
-	return c.JSON(fiber.Map{
-		"message": "success",
-	})
+	eh.Logger.Infof("user %v %s event %v",request.UserId,response.Status,request.EventId)
+	return c.JSON(response)
+}
+
+// JoinByInvite godoc
+// @Summary Join event by invite
+// @Description Joins the current user to the event the invite code belongs to, the only way into invite-only events
+// @Tags events
+// @Produce json
+// @Security ApiKeyAuth
+// @Param code path string true "Invite code"
+// @Param role query string false "Role slot to claim"
+// @Success 200 {object} dto.JoinEventResponse
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /events/join/{code} [patch]
+func (eh *EventsHandler) JoinByInvite(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, eh.Logger, "join-by-invite")
+	code := c.Params("code")
+	request := dto.JoinByInviteRequest{}
+	if err := c.QueryParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := eh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	response, err := eh.EventService.JoinByInvite(ctx, code, request.Role, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to join to event: " + err.Error(),
+		})
+	}
+	eh.Logger.Infof("user %v %s event by invite",callerId(c),response.Status)
+	return c.JSON(response)
 }
 
 // Unjoin godoc
 // @Summary Exit event
-// @Description Removes a user from the event participants
+// @Description Removes a user from the event participants or the waitlist, the first waitlisted player takes the free slot
 // @Tags events
 // @Accept json
 // @Produce json
@@ -243,9 +304,162 @@ func (eh *EventsHandler) Unjoin(c *fiber.Ctx) error {
 	})
 }
 
+// EditEvent godoc
+// @Summary Edit an event
+// @Description Changes body, game, max or time of an event, only the author can do it. Members are notified about the changes
+// @Tags events
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Event ID"
+// @Param request body dto.EditEventRequest true "Fields to change"
+// @Success 200 {object} entities.Event
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /events/{id} [patch]
+func (eh *EventsHandler) EditEvent(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, eh.Logger, "edit-event")
+	id := c.Params("id")
+	request := dto.EditEventRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := eh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	event, err := eh.EventService.EditEvent(ctx, id, request, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to edit event: " + err.Error(),
+		})
+	}
+	eh.Logger.Infof("event edited: %v",event.Id)
+	return c.JSON(event)
+}
+
+// CancelEvent godoc
+// @Summary Cancel an event
+// @Description Cancels an event that has not started yet, only the author can do it. Members are notified
+// @Tags events
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Event ID"
+// @Param request body dto.CancelEventRequest false "Cancellation reason"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /events/{id}/cancel [post]
+func (eh *EventsHandler) CancelEvent(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, eh.Logger, "cancel-event")
+	id := c.Params("id")
+	request := dto.CancelEventRequest{}
+	if len(c.Body()) != 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return errh.ParseRequestError(eH, err)
+		}
+	}
+	if err := eh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	if err := eh.EventService.CancelEvent(ctx, id, request, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to cancel event: " + err.Error(),
+		})
+	}
+	eh.Logger.Infof("event cancelled: %v",id)
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
+
+// CheckIn godoc
+// @Summary Check in to an event
+// @Description Confirms that the current user showed up, accepted only around the start of the event. Members who do not check in are counted as no-shows
+// @Tags events
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Event ID"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /events/{id}/checkin [post]
+func (eh *EventsHandler) CheckIn(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, eh.Logger, "check-in")
+	id := c.Params("id")
+	if err := eh.EventService.CheckIn(ctx, id, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to check in: " + err.Error(),
+		})
+	}
+	eh.Logger.Infof("user %v checked in to event %v",callerId(c),id)
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
+
+// SkipOccurrence godoc
+// @Summary Skip an occurrence
+// @Description Cancels a single occurrence of a recurring event, only the author can do it
+// @Tags events
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SkipOccurrenceRequest true "Occurrence to skip"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /events/skip [patch]
+func (eh *EventsHandler) SkipOccurrence(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, eh.Logger, "skip-occurrence")
+	request := dto.SkipOccurrenceRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := eh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	if err := eh.EventService.SkipOccurrence(ctx, request, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to skip occurrence: " + err.Error(),
+		})
+	}
+	eh.Logger.Infof("occurrence %v of event %v skipped",request.Date,request.EventId)
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
+
 // GetFilteredEvents godoc
 // @Summary Get filtered events
-// @Description Get filtered list of events by params
+// @Description Get filtered list of events by params. game takes several comma separated names, from and to select a time range, min-max keeps events with at least that many slots
 // @Tags events
 // @Accept json
 // @Produce json
@@ -267,7 +481,7 @@ func (eh *EventsHandler) GetFilteredEvents(c *fiber.Ctx) error {
 	if err := eh.Validator.Struct(params); err != nil {
 		return errh.ValidateRequestError(eH, err)
 	}
-	events, err := eh.EventService.GetFiltered(ctx, params)
+	events, err := eh.EventService.GetFiltered(ctx, params, callerId(c))
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
@@ -283,7 +497,7 @@ func (eh *EventsHandler) GetFilteredEvents(c *fiber.Ctx) error {
 
 // GetSortedEvents godoc
 // @Summary Get sorted events
-// @Description Get sorted list of events by params
+// @Description Get sorted list of events by params. field and direction take comma separated lists to sort by several fields
 // @Tags events
 // @Accept json
 // @Produce json
@@ -305,15 +519,17 @@ func (eh *EventsHandler) GetSortedEvents(c *fiber.Ctx) error {
 	if err := eh.Validator.Struct(params); err != nil {
 		return errh.ValidateRequestError(eH, err)
 	}
-	params.Direction = strings.ToUpper(params.Direction)
-	if params.Direction == "" || params.Direction != "DESC" {
-		params.Direction = "ASC"
-	}
-	events, err := eh.EventService.GetSorted(ctx, params)
+	events, err := eh.EventService.GetSorted(ctx, params, callerId(c))
 	if err!=nil{
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
+		if errors.Is(err, query.ErrInvalid) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
 			"error": "failed to fetch sorted events: " + err.Error(),
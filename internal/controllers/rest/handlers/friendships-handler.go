@@ -4,6 +4,7 @@ import (
 	"context"
 	"crap/internal/domain/services"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
 	errh "crap/pkg/errors-handlers"
 	"errors"
 	"time"
@@ -74,7 +75,7 @@ func (fh *FriendshipsHandler) AddFriend(c *fiber.Ctx) error {
 // @Produce json
 // @Security ApiKeyAuth
 // @Param request query dto.GetFriendsRequest true "Get Friends Request"
-// @Success 200 {array} entities.User "List of friends"
+// @Success 200 {object} query.Page[entities.User] "Page of friends with cursors"
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -95,6 +96,12 @@ func (fh *FriendshipsHandler) GetFriends(c *fiber.Ctx) error{
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
+		if errors.Is(err,query.ErrInvalid){
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error":err.Error(),
+			})
+		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
 			"error":"failed to get friends: "+ err.Error(),
@@ -4,9 +4,9 @@ import (
 	"context"
 	"crap/internal/domain/services"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
 	errh "crap/pkg/errors-handlers"
 	"errors"
-	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -113,8 +113,8 @@ func (gh *GamesHandler) DeleteGame(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param request query dto.PaginationRequest true "Pagination parameters"
-// @Success 200 {array} entities.Game "List of games"
+// @Param request query dto.CursorRequest true "Pagination parameters"
+// @Success 200 {object} query.Page[entities.Game] "Page of games with cursors"
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -123,7 +123,7 @@ func (gh *GamesHandler) GetGames(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
 	defer cancel()
 	eH := errh.NewErrorHander(c, gh.Logger, "get-games")
-	params := dto.PaginationRequest{}
+	params := dto.CursorRequest{}
 	if err := c.QueryParser(&params); err != nil {
 		return errh.ParseRequestError(eH, err)
 	}
@@ -135,6 +135,12 @@ func (gh *GamesHandler) GetGames(c *fiber.Ctx) error {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
+		if errors.Is(err, query.ErrInvalid) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
 			"error": "failed to get games: " + err.Error(),
@@ -178,13 +184,13 @@ func (gh *GamesHandler) GetGame(c *fiber.Ctx) error {
 
 // GetFilteredGames godoc
 // @Summary Get filtered games
-// @Description Get filtered list of games by params
+// @Description Get filtered list of games by params. game-name takes several comma separated names
 // @Tags games
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param request query dto.GamesFilterRequest true "Filter parameters"
-// @Success 200 {array} entities.Game "List of games"
+// @Success 200 {object} query.Page[entities.Game] "Page of games with cursors"
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -217,13 +223,13 @@ func (gh *GamesHandler) GetFilteredGames(c *fiber.Ctx) error {
 
 // GetSortedGames godoc
 // @Summary Get sorted games
-// @Description Get sorted list of games by params
+// @Description Get sorted list of games by params. field and direction take comma separated lists to sort by several fields
 // @Tags games
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param request query dto.GamesSortRequest true "Sort parameters"
-// @Success 200 {array} entities.Game "List of games"
+// @Success 200 {object} query.Page[entities.Game] "Page of games with cursors"
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -239,15 +245,17 @@ func (gh *GamesHandler) GetSortedGames(c *fiber.Ctx) error {
 	if err := gh.Validator.Struct(params); err != nil {
 		return errh.ValidateRequestError(eH, err)
 	}
-	params.Direction = strings.ToUpper(params.Direction)
-	if params.Direction == "" || params.Direction != "DESC" {
-		params.Direction = "ASC"
-	}
 	games, err := gh.GameService.GetSorted(ctx, params)
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
+		if errors.Is(err, query.ErrInvalid) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
 			"error": "failed to fetch sorted games: " + err.Error(),
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"crap/internal/domain/services"
+	"crap/internal/dto"
+	errh "crap/pkg/errors-handlers"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type LfgHandler struct {
+	LfgService services.LfgService
+	Logger     *logrus.Logger
+	Validator  *validator.Validate
+}
+
+func NewLfgHandler(ls services.LfgService, l *logrus.Logger, v *validator.Validate) LfgHandler {
+	return LfgHandler{
+		LfgService: ls,
+		Logger:     l,
+		Validator:  v,
+	}
+}
+
+// Enqueue godoc
+// @Summary Look for a group
+// @Description Puts the current user into the matchmaking queue, a background matcher creates an event once enough compatible players are waiting
+// @Tags lfg
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.EnqueueLfgRequest true "Queue parameters"
+// @Success 200 {object} entities.LfgTicket
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /lfg [post]
+func (lh *LfgHandler) Enqueue(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, lh.Logger, "lfg-enqueue")
+	request := dto.EnqueueLfgRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := lh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	ticket, err := lh.LfgService.Enqueue(ctx, request, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to enqueue: " + err.Error(),
+		})
+	}
+	lh.Logger.Infof("user %v is looking for a group", ticket.UserId)
+	return c.JSON(ticket)
+}
+
+// Status godoc
+// @Summary Queue status
+// @Description Returns the current user's ticket and how many players wait for the same game and party size
+// @Tags lfg
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.LfgStatusResponse
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /lfg [get]
+func (lh *LfgHandler) Status(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, lh.Logger, "lfg-status")
+	status, err := lh.LfgService.Status(ctx, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to get queue status: " + err.Error(),
+		})
+	}
+	return c.JSON(status)
+}
+
+// Leave godoc
+// @Summary Leave the queue
+// @Description Removes the current user from the matchmaking queue
+// @Tags lfg
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /lfg [delete]
+func (lh *LfgHandler) Leave(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, lh.Logger, "lfg-leave")
+	if err := lh.LfgService.Leave(ctx, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to leave the queue: " + err.Error(),
+		})
+	}
+	lh.Logger.Infof("user %v left the queue", callerId(c))
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crap/internal/domain/services"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
 	errh "crap/pkg/errors-handlers"
 	"errors"
 	"time"
@@ -105,8 +106,8 @@ func(nh *NewsHandler) GetNews(c *fiber.Ctx) error{
 // @Tags news
 // @Accept json
 // @Produce json
-// @Param request query dto.PaginationRequest true "Pagination parameters"
-// @Success 200 {array} entities.News "List of news"
+// @Param request query dto.CursorRequest true "Pagination parameters"
+// @Success 200 {object} query.Page[entities.News] "Page of news with cursors"
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -115,7 +116,7 @@ func(nh *NewsHandler) GetSomeNews(c *fiber.Ctx) error{
 	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
 	defer cancel()
 	eH := errh.NewErrorHander(c, nh.Logger, "get-news")
-	params:=dto.PaginationRequest{}
+	params:=dto.CursorRequest{}
 	if err:=c.QueryParser(&params);err!=nil{
 		return errh.ParseRequestError(eH,err)
 	}
@@ -127,6 +128,12 @@ func(nh *NewsHandler) GetSomeNews(c *fiber.Ctx) error{
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
+		if errors.Is(err,query.ErrInvalid){
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error":err.Error(),
+			})
+		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
 			"error":"failed to get some news: "+ err.Error(),
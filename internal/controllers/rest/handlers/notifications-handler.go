@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"bufio"
 	"context"
 	"crap/internal/domain/services"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
 	errh "crap/pkg/errors-handlers"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 )
@@ -100,13 +105,13 @@ func (nh *NotificationsHandler) DeleteAllNotifications(c *fiber.Ctx) error {
 
 // GetNotifications godoc
 // @Summary Get notifications
-// @Description Get paginated list of notifications
+// @Description Get paginated list of notifications, optionally only the read or unread ones of one event
 // @Tags notifications
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param request query dto.GetNotificationsRequest true "Pagination and filter parameters"
-// @Success 200 {array} entities.Notification "List of notifications"
+// @Success 200 {object} query.Page[entities.Notification] "Page of notifications with cursors"
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -127,6 +132,12 @@ func (nh *NotificationsHandler) GetNotifications(c *fiber.Ctx) error {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
+		if errors.Is(err, query.ErrInvalid) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
 			"error": "failed to get notifications: " + err.Error(),
@@ -137,3 +148,462 @@ func (nh *NotificationsHandler) GetNotifications(c *fiber.Ctx) error {
 }
 
 
+// MarkRead godoc
+// @Summary Mark notifications read
+// @Description Marks the listed notifications of the caller read, or every notification up to before when no ids are given
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.MarkNotificationsReadRequest true "Notification ids or a time"
+// @Success 200 {object} dto.MarkReadResponse
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/read [patch]
+func (nh *NotificationsHandler) MarkRead(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, nh.Logger, "mark-notifications-read")
+	request := dto.MarkNotificationsReadRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := nh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	marked, err := nh.NotificationService.MarkRead(ctx, request, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to mark notifications read: " + err.Error(),
+		})
+	}
+	return c.JSON(dto.MarkReadResponse{Marked: marked})
+}
+
+// GetUnread godoc
+// @Summary Count unread notifications
+// @Description Returns how many notifications of the caller are unread
+// @Tags notifications
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.UnreadResponse
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/unread [get]
+func (nh *NotificationsHandler) GetUnread(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, nh.Logger, "count-unread-notifications")
+	unread, err := nh.NotificationService.CountUnread(ctx, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to count unread notifications: " + err.Error(),
+		})
+	}
+	return c.JSON(dto.UnreadResponse{Unread: unread})
+}
+
+// GetPreferences godoc
+// @Summary Get notification channels
+// @Description Returns the outside channels every notification type is delivered to, in-app notifications are always kept
+// @Tags notifications
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} entities.ChannelPreference
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/preferences [get]
+func (nh *NotificationsHandler) GetPreferences(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, nh.Logger, "get-notification-preferences")
+	preferences, err := nh.NotificationService.GetPreferences(ctx, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to get notification preferences: " + err.Error(),
+		})
+	}
+	return c.JSON(preferences)
+}
+
+// SetPreference godoc
+// @Summary Set notification channels
+// @Description Picks the outside channels (telegram, email, webhook) for one notification type (reminder, start, event, waitlist, lfg, friend, comment). An empty list keeps only the in-app notification
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SetChannelPreferenceRequest true "Type and channels"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/preferences [patch]
+func (nh *NotificationsHandler) SetPreference(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, nh.Logger, "set-notification-preference")
+	request := dto.SetChannelPreferenceRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := nh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	if err := nh.NotificationService.SetPreference(ctx, request, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to set notification preference: " + err.Error(),
+		})
+	}
+	nh.Logger.Infof("user %v routes %v notifications to %v", callerId(c), request.Type, request.Channels)
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
+
+// GetSchedule godoc
+// @Summary Get notification schedule
+// @Description Returns the quiet hours and the digest mode of the outside channels
+// @Tags notifications
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} entities.NotificationSchedule
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/schedule [get]
+func (nh *NotificationsHandler) GetSchedule(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, nh.Logger, "get-notification-schedule")
+	schedule, err := nh.NotificationService.GetSchedule(ctx, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to get notification schedule: " + err.Error(),
+		})
+	}
+	return c.JSON(schedule)
+}
+
+// SetSchedule godoc
+// @Summary Set notification schedule
+// @Description Sets the quiet hours, in the user's time zone, when the outside channels hold their messages, and the digest mode (off, hourly, daily) that batches friend requests and comments. Reminders and event starts get through the quiet hours with reminders_in_quiet
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SetScheduleRequest true "Quiet hours and digest"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/schedule [patch]
+func (nh *NotificationsHandler) SetSchedule(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, nh.Logger, "set-notification-schedule")
+	request := dto.SetScheduleRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := nh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	if err := nh.NotificationService.SetSchedule(ctx, request, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to set notification schedule: " + err.Error(),
+		})
+	}
+	nh.Logger.Infof("user %v set quiet hours %v-%v and %v digest", callerId(c), request.QuietFrom, request.QuietTo, request.Digest)
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
+
+// SetEmail godoc
+// @Summary Set notification email
+// @Description Sets the address the email channel delivers to
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SetEmailRequest true "Email address"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/channels/email [patch]
+func (nh *NotificationsHandler) SetEmail(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, nh.Logger, "set-notification-email")
+	request := dto.SetEmailRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := nh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	if err := nh.NotificationService.SetEmail(ctx, request, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to set notification email: " + err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
+
+// SetWebhook godoc
+// @Summary Set notification webhook
+// @Description Sets the https url the webhook channel posts to and returns a new secret. Every request carries the X-Webhook-Timestamp header and X-Webhook-Signature, the hex HMAC-SHA256 of the timestamp, a dot and the body
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SetWebhookRequest true "Webhook url"
+// @Success 200 {object} dto.WebhookResponse
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/channels/webhook [patch]
+func (nh *NotificationsHandler) SetWebhook(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, nh.Logger, "set-notification-webhook")
+	request := dto.SetWebhookRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := nh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	response, err := nh.NotificationService.SetWebhook(ctx, request, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to set notification webhook: " + err.Error(),
+		})
+	}
+	return c.JSON(response)
+}
+
+// DeleteContact godoc
+// @Summary Remove a notification address
+// @Description Removes the email address or the webhook, the channel stops delivering to the user
+// @Tags notifications
+// @Produce json
+// @Security ApiKeyAuth
+// @Param channel path string true "email or webhook"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/channels/{channel} [delete]
+func (nh *NotificationsHandler) DeleteContact(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, nh.Logger, "delete-notification-contact")
+	channel := c.Params("channel")
+	if err := nh.Validator.Var(channel, "oneof=email webhook"); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	if err := nh.NotificationService.DeleteContact(ctx, channel, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to remove notification address: " + err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
+
+// streamKeepAlive is how often an idle stream is pinged, so proxies keep it
+// open and a gone client is noticed.
+const streamKeepAlive = 25 * time.Second
+
+// lastEventId is the notification a stream resumes after. Browsers send the
+// Last-Event-ID header when an EventSource reconnects, a WebSocket has to
+// pass it in the query.
+func lastEventId(c *fiber.Ctx) string {
+	if id := c.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.Query("last-event-id")
+}
+
+// streamFailed logs notifications of the stream of the user that could not
+// be read, the stream goes on without them.
+func (nh *NotificationsHandler) streamFailed(id string) func(error) {
+	return func(err error) {
+		nh.Logger.WithError(err).Errorf("failed to stream a notification to %v", id)
+	}
+}
+
+// Stream godoc
+// @Summary Stream notifications
+// @Description Server-sent events with every new notification of the caller, the event id is the notification id. A reconnect with Last-Event-ID (header or last-event-id query) first replays the notifications missed since then
+// @Tags notifications
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param Last-Event-ID header string false "Last received notification ID"
+// @Param last-event-id query string false "Last received notification ID"
+// @Success 200 {object} entities.Notification "Stream of notification events"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/stream [get]
+func (nh *NotificationsHandler) Stream(c *fiber.Ctx) error {
+	eH := errh.NewErrorHander(c, nh.Logger, "stream-notifications")
+	lastId := lastEventId(c)
+	if err := nh.Validator.Var(lastId, "omitempty,uuid"); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	id := callerId(c)
+	// the request context is reused once the handler returns, only its
+	// shutdown signal outlives it
+	shutdown := c.Context().Done()
+	ctx, cancel := context.WithCancel(context.Background())
+	notifications, err := nh.NotificationService.Stream(ctx, id, lastId, nh.streamFailed(id))
+	if err != nil {
+		cancel()
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to stream notifications: " + err.Error(),
+		})
+	}
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		ticker := time.NewTicker(streamKeepAlive)
+		defer ticker.Stop()
+		fmt.Fprintf(w, "retry: %d\n\n", (5 * time.Second).Milliseconds())
+		for {
+			if err := w.Flush(); err != nil {
+				nh.Logger.Infof("notification stream of %v closed: %v", id, err)
+				return
+			}
+			select {
+			case n, ok := <-notifications:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(n)
+				if err != nil {
+					nh.Logger.WithError(err).Error("failed to encode notification")
+					continue
+				}
+				fmt.Fprintf(w, "id: %s\nevent: notification\ndata: %s\n\n", n.Id, data)
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+			case <-shutdown:
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// StreamSocket godoc
+// @Summary Stream notifications over WebSocket
+// @Description Same as the event stream, every text message is one notification. Pass last-event-id in the query to replay the notifications missed since then
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Param last-event-id query string false "Last received notification ID"
+// @Success 101 {object} entities.Notification "Switching protocols, then a message per notification"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 426 {object} object "{\"error\":\"string\"}"
+// @Router /notifications/stream/ws [get]
+func (nh *NotificationsHandler) StreamSocket(c *fiber.Ctx) error {
+	eH := errh.NewErrorHander(c, nh.Logger, "stream-notifications-ws")
+	if !websocket.IsWebSocketUpgrade(c) {
+		c.Status(fiber.StatusUpgradeRequired)
+		return c.JSON(fiber.Map{
+			"error": "websocket upgrade required",
+		})
+	}
+	lastId := lastEventId(c)
+	if err := nh.Validator.Var(lastId, "omitempty,uuid"); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	id := callerId(c)
+	shutdown := c.Context().Done()
+	return websocket.New(func(conn *websocket.Conn) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		notifications, err := nh.NotificationService.Stream(ctx, id, lastId, nh.streamFailed(id))
+		if err != nil {
+			nh.Logger.WithError(err).Error("failed to stream notifications")
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to stream notifications"))
+			return
+		}
+		// the client sends nothing, reading only notices when it leaves
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		ticker := time.NewTicker(streamKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case n, ok := <-notifications:
+				if !ok {
+					return
+				}
+				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				if err := conn.WriteJSON(n); err != nil {
+					nh.Logger.Infof("notification socket of %v closed: %v", id, err)
+					return
+				}
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+					return
+				}
+			case <-shutdown:
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+		}
+	})(c)
+}
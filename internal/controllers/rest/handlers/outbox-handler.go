@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"crap/internal/domain/services"
+	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
+	errh "crap/pkg/errors-handlers"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type OutboxHandler struct {
+	OutboxService services.OutboxService
+	Logger        *logrus.Logger
+	Validator     *validator.Validate
+}
+
+func NewOutboxHandler(os services.OutboxService, l *logrus.Logger, v *validator.Validate) OutboxHandler {
+	return OutboxHandler{
+		OutboxService: os,
+		Logger:        l,
+		Validator:     v,
+	}
+}
+
+// GetDeadLetters godoc
+// @Summary Get dead letters
+// @Description Returns the outbox messages that ran out of delivery attempts, newest first. Admins only
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request query dto.CursorRequest true "Pagination parameters"
+// @Success 200 {object} query.Page[entities.OutboxMessage] "Page of dead letters with cursors"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 403 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /admin/outbox/dead [get]
+func (oh *OutboxHandler) GetDeadLetters(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, oh.Logger, "get-dead-letters")
+	params := dto.CursorRequest{}
+	if err := c.QueryParser(&params); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := oh.Validator.Struct(params); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	letters, err := oh.OutboxService.FetchDead(ctx, params, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		if errors.Is(err, services.ErrNotAdmin) {
+			c.Status(fiber.StatusForbidden)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to get dead letters: " + err.Error(),
+		})
+	}
+	return c.JSON(letters)
+}
+
+// ReplayDeadLetter godoc
+// @Summary Replay a dead letter
+// @Description Puts a dead outbox message back in the queue with a fresh set of attempts. Admins only
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Outbox message ID"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 403 {object} object "{\"error\":\"string\"}"
+// @Failure 404 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /admin/outbox/{id}/replay [post]
+func (oh *OutboxHandler) ReplayDeadLetter(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, oh.Logger, "replay-dead-letter")
+	id := c.Params("id")
+	if err := oh.OutboxService.Replay(ctx, id, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		if errors.Is(err, services.ErrNotAdmin) {
+			c.Status(fiber.StatusForbidden)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrNoDeadLetter) {
+			c.Status(fiber.StatusNotFound)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to replay dead letter: " + err.Error(),
+		})
+	}
+	oh.Logger.Infof("dead letter %v replayed by %v", id, callerId(c))
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
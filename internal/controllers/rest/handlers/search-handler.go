@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"crap/internal/domain/services"
+	"crap/internal/dto"
+	errh "crap/pkg/errors-handlers"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type SearchHandler struct {
+	SearchService services.SearchService
+	Logger        *logrus.Logger
+	Validator     *validator.Validate
+}
+
+func NewSearchHandler(ss services.SearchService, l *logrus.Logger, v *validator.Validate) SearchHandler {
+	return SearchHandler{
+		SearchService: ss,
+		Logger:        l,
+		Validator:     v,
+	}
+}
+
+// Search godoc
+// @Summary Search
+// @Description Full-text search across events, games, news and users in Russian and English. Every word matches as a prefix, so it can back autocomplete. Hits are ranked and typed as event, game, news or user
+// @Tags search
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request query dto.SearchRequest true "Search parameters"
+// @Success 200 {array} entities.SearchHit
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /search [get]
+func (sh *SearchHandler) Search(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, sh.Logger, "search")
+	params := dto.SearchRequest{}
+	if err := c.QueryParser(&params); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := sh.Validator.Struct(params); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	hits, err := sh.SearchService.Search(ctx, params, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to search: " + err.Error(),
+		})
+	}
+	return c.JSON(hits)
+}
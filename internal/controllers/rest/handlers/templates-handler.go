@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"crap/internal/domain/services"
+	"crap/internal/dto"
+	errh "crap/pkg/errors-handlers"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type TemplatesHandler struct {
+	TemplateService services.TemplateService
+	Logger          *logrus.Logger
+	Validator       *validator.Validate
+}
+
+func NewTemplatesHandler(ts services.TemplateService, l *logrus.Logger, v *validator.Validate) TemplatesHandler {
+	return TemplatesHandler{
+		TemplateService: ts,
+		Logger:          l,
+		Validator:       v,
+	}
+}
+
+// SaveTemplate godoc
+// @Summary Save an event as a template
+// @Description Stores the game, description, capacity, roles, duration and visibility of the current user's event under a name, so it can be created again by passing template-id to the event creation
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SaveTemplateRequest true "Template data"
+// @Success 200 {object} entities.EventTemplate
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /events/templates [post]
+func (th *TemplatesHandler) SaveTemplate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, th.Logger, "save-template")
+	request := dto.SaveTemplateRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := th.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	template, err := th.TemplateService.SaveTemplate(ctx, request, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to save template: " + err.Error(),
+		})
+	}
+	th.Logger.Infof("template saved: %v", template.Id)
+	return c.JSON(template)
+}
+
+// GetTemplates godoc
+// @Summary Get templates
+// @Description Returns the current user's templates and the ones friends shared with them
+// @Tags templates
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} entities.EventTemplate
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /events/templates [get]
+func (th *TemplatesHandler) GetTemplates(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, th.Logger, "get-templates")
+	templates, err := th.TemplateService.GetTemplates(ctx, callerId(c))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to get templates: " + err.Error(),
+		})
+	}
+	return c.JSON(templates)
+}
+
+// ShareTemplate godoc
+// @Summary Share a template
+// @Description Lets a friend of the author create events from the template
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Template ID"
+// @Param request body dto.ShareTemplateRequest true "Friend to share with"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /events/templates/{id}/share [post]
+func (th *TemplatesHandler) ShareTemplate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, th.Logger, "share-template")
+	request := dto.ShareTemplateRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := th.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	id := c.Params("id")
+	if err := th.TemplateService.ShareTemplate(ctx, id, request, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to share template: " + err.Error(),
+		})
+	}
+	th.Logger.Infof("template %v shared with %v", id, request.FriendId)
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
+
+// DeleteTemplate godoc
+// @Summary Delete a template
+// @Description Deletes a template of the current user, events created from it are kept
+// @Tags templates
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Template ID"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /events/templates/{id} [delete]
+func (th *TemplatesHandler) DeleteTemplate(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, th.Logger, "delete-template")
+	id := c.Params("id")
+	if err := th.TemplateService.DeleteTemplate(ctx, id, callerId(c)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to delete template: " + err.Error(),
+		})
+	}
+	th.Logger.Infof("template deleted: %v", id)
+	return c.JSON(fiber.Map{
+		"message": "success",
+	})
+}
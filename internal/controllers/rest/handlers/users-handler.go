@@ -4,8 +4,10 @@ import (
 	"context"
 	"crap/internal/domain/services"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
 	errh "crap/pkg/errors-handlers"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -29,7 +31,7 @@ func NewUsersHandler(us services.UserService, l *logrus.Logger, v *validator.Val
 
 // GetUser godoc
 // @Summary Get user by ID
-// @Description Get detailed information about specific user
+// @Description Get detailed information about specific user, telegram_linked tells whether a Telegram chat is bound
 // @Tags users
 // @Accept json
 // @Produce json
@@ -66,8 +68,8 @@ func(uh *UsersHandler) GetUser(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param request query dto.PaginationRequest true "Pagination parameters"
-// @Success 200 {array} entities.User "List of users"
+// @Param request query dto.CursorRequest true "Pagination parameters"
+// @Success 200 {object} query.Page[entities.User] "Page of users with cursors"
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
@@ -76,7 +78,7 @@ func(uh *UsersHandler) GetUsers(c *fiber.Ctx) error{
 	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
 	defer cancel()
 	eH := errh.NewErrorHander(c, uh.Logger, "get-user")
-	params:=dto.PaginationRequest{}
+	params:=dto.CursorRequest{}
 	if err:=c.QueryParser(&params);err!=nil{
 		return errh.ParseRequestError(eH,err)
 	}
@@ -88,6 +90,12 @@ func(uh *UsersHandler) GetUsers(c *fiber.Ctx) error{
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
+		if errors.Is(err,query.ErrInvalid){
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error":err.Error(),
+			})
+		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
 			"error": "failed to get users: " + err.Error(),
@@ -185,6 +193,158 @@ func(uh *UsersHandler) RecordDiscord(c *fiber.Ctx) error{
 	})
 }
 
+// RecordTimeZone godoc
+// @Summary Record user time zone
+// @Description Store the IANA time zone used to render event times for the caller
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.RecordTimeZoneRequest true "Time zone data"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /users/timezone [patch]
+func(uh *UsersHandler) RecordTimeZone(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "record-time-zone")
+	request := dto.RecordTimeZoneRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := uh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	if err:=uh.UserService.RecordTimeZone(ctx,request,callerId(c));err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to record time zone: " + err.Error(),
+		})
+	}
+	uh.Logger.Infof("time zone recorded: %v", callerId(c))
+	return c.JSON(fiber.Map{
+		"message":"success",
+	})
+}
+
+// RecordLanguage godoc
+// @Summary Record user language
+// @Description Store the language (ru, en) notifications and bot replies are rendered in for the caller
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.RecordLanguageRequest true "Language"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /users/language [patch]
+func(uh *UsersHandler) RecordLanguage(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "record-language")
+	request := dto.RecordLanguageRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := uh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	if err:=uh.UserService.RecordLanguage(ctx,request,callerId(c));err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		if errors.Is(err, services.ErrUnsupportedLanguage) {
+			c.Status(fiber.StatusBadRequest)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to record language: " + err.Error(),
+		})
+	}
+	uh.Logger.Infof("language recorded: %v", callerId(c))
+	return c.JSON(fiber.Map{
+		"message":"success",
+	})
+}
+
+// GetReminders godoc
+// @Summary Get reminder lead times
+// @Description Returns how many minutes before an event the caller is reminded about it
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.RemindersResponse
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /users/reminders [get]
+func(uh *UsersHandler) GetReminders(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "get-reminders")
+	id:=callerId(c)
+	offsets,err:=uh.UserService.GetReminders(ctx,id)
+	if err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to get reminders: " + err.Error(),
+		})
+	}
+	uh.Logger.Infof("reminders received: %v", id)
+	return c.JSON(dto.RemindersResponse{Offsets: offsets})
+}
+
+// SetReminders godoc
+// @Summary Set reminder lead times
+// @Description Replaces the caller's reminders, offsets are minutes before the event (up to 5, at most 30 days)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.SetRemindersRequest true "Reminder offsets"
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /users/reminders [patch]
+func(uh *UsersHandler) SetReminders(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "set-reminders")
+	request := dto.SetRemindersRequest{}
+	if err := c.BodyParser(&request); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := uh.Validator.Struct(request); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	if err:=uh.UserService.SetReminders(ctx,request,callerId(c));err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to set reminders: " + err.Error(),
+		})
+	}
+	uh.Logger.Infof("reminders set: %v", callerId(c))
+	return c.JSON(fiber.Map{
+		"message":"success",
+	})
+}
+
 // DeleteAvatar godoc
 // @Summary Delete user avatar
 // @Description Remove user's avatar image
@@ -218,41 +378,237 @@ func(uh *UsersHandler) DeleteAvatar(c *fiber.Ctx) error{
 	})
 }
 
-// EditRating godoc
-// @Summary Edit user rating
-// @Description Update user's rating value
+// RateUser godoc
+// @Summary Rate a teammate
+// @Description Rates a player the current user shared a finished event with, once per event
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param request body dto.EditRatingRequest true "Rating edit data"
-// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Param request body dto.RateUserRequest true "Rating data"
+// @Success 200 {object} entities.Rating
 // @Failure 400 {object} object "{\"error\":\"string\"}"
 // @Failure 408 {object} object "{\"error\":\"string\"}"
 // @Failure 500 {object} object "{\"error\":\"string\"}"
-// @Router /users/rating [patch]
-func(uh *UsersHandler) EditRating(c *fiber.Ctx) error{
+// @Router /users/ratings [post]
+func(uh *UsersHandler) RateUser(c *fiber.Ctx) error{
 	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
 	defer cancel()
-	eH := errh.NewErrorHander(c, uh.Logger, "edit-rating")
-	request := dto.EditRatingRequest{}
+	eH := errh.NewErrorHander(c, uh.Logger, "rate-user")
+	request := dto.RateUserRequest{}
 	if err := c.BodyParser(&request); err != nil {
 		return errh.ParseRequestError(eH, err)
 	}
 	if err := uh.Validator.Struct(request); err != nil {
 		return errh.ValidateRequestError(eH, err)
 	}
-	if err:=uh.UserService.EditRating(ctx,request);err!=nil{
+	rating,err:=uh.UserService.RateUser(ctx,request,callerId(c))
+	if err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to rate user: " + err.Error(),
+		})
+	}
+	uh.Logger.Infof("user rated: %v", request.RateeId)
+	return c.JSON(rating)
+}
+
+// GetRatings godoc
+// @Summary Get user ratings
+// @Description Returns the ratings a user gave or received, newest first
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request query dto.GetRatingsRequest true "Ratings parameters"
+// @Success 200 {array} entities.Rating
+// @Failure 400 {object} object "{\"error\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /users/ratings [get]
+func(uh *UsersHandler) GetRatings(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "get-ratings")
+	params := dto.GetRatingsRequest{}
+	if err := c.QueryParser(&params); err != nil {
+		return errh.ParseRequestError(eH, err)
+	}
+	if err := uh.Validator.Struct(params); err != nil {
+		return errh.ValidateRequestError(eH, err)
+	}
+	ratings,err:=uh.UserService.GetRatings(ctx,params)
+	if err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to get ratings: " + err.Error(),
+		})
+	}
+	uh.Logger.Infof("ratings received: %v", params.UserId)
+	return c.JSON(ratings)
+}
+
+// GetCalendarLink godoc
+// @Summary Get the calendar feed link
+// @Description Returns the secret ICS feed address of the current user's joined events, to be added to a calendar app as a subscription
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.CalendarLinkResponse
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /users/calendar [get]
+func(uh *UsersHandler) GetCalendarLink(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "get-calendar-link")
+	id:=callerId(c)
+	token,err:=uh.UserService.GetCalendarToken(ctx,id)
+	if err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to get calendar link: " + err.Error(),
+		})
+	}
+	return c.JSON(dto.CalendarLinkResponse{Url: calendarUrl(c,id,token)})
+}
+
+// ResetCalendarLink godoc
+// @Summary Reset the calendar feed link
+// @Description Generates a new secret for the ICS feed, the previous link stops working
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.CalendarLinkResponse
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /users/calendar [post]
+func(uh *UsersHandler) ResetCalendarLink(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "reset-calendar-link")
+	id:=callerId(c)
+	token,err:=uh.UserService.ResetCalendarToken(ctx,id)
+	if err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to reset calendar link: " + err.Error(),
+		})
+	}
+	uh.Logger.Infof("calendar link reset: %v", id)
+	return c.JSON(dto.CalendarLinkResponse{Url: calendarUrl(c,id,token)})
+}
+
+// LinkTelegram godoc
+// @Summary Get a Telegram link
+// @Description Returns a one-time t.me link that binds the Telegram chat opening it to the current user. The link expires after a few minutes, the profile shows telegram_linked once it is used
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.TelegramLinkResponse
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Failure 503 {object} object "{\"error\":\"string\"}"
+// @Router /users/telegram [post]
+func(uh *UsersHandler) LinkTelegram(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "link-telegram")
+	link,err:=uh.UserService.LinkTelegram(ctx,callerId(c))
+	if err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		if errors.Is(err, services.ErrTelegramNotConfigured) {
+			c.Status(fiber.StatusServiceUnavailable)
+			return c.JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to create telegram link: " + err.Error(),
+		})
+	}
+	return c.JSON(link)
+}
+
+// UnlinkTelegram godoc
+// @Summary Unlink Telegram
+// @Description Unbinds the Telegram chat of the current user, Telegram notifications stop until a new link is used
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object "{\"message\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /users/telegram [delete]
+func(uh *UsersHandler) UnlinkTelegram(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "unlink-telegram")
+	if err:=uh.UserService.UnlinkTelegram(ctx,callerId(c));err!=nil{
 		if errors.Is(err, context.DeadlineExceeded) {
 			return errh.RequestTimedOut(eH, err)
 		}
 		c.Status(fiber.StatusInternalServerError)
 		return c.JSON(fiber.Map{
-			"error": "failed to edit rating: " + err.Error(),
+			"error": "failed to unlink telegram: " + err.Error(),
 		})
 	}
-	uh.Logger.Infof("rating edited: %v", request.UserId)
+	uh.Logger.Infof("telegram unlinked: %v", callerId(c))
 	return c.JSON(fiber.Map{
-		"message":"success",
+		"message": "success",
 	})
+}
+
+// GetCalendar godoc
+// @Summary Calendar feed
+// @Description ICS feed of every event the user joined, cancelled events are kept with the cancelled status. Authorized by the token from the feed link instead of the session
+// @Tags users
+// @Produce text/calendar
+// @Param id path string true "User ID"
+// @Param token query string true "Calendar token"
+// @Success 200 {string} string "ICS document"
+// @Failure 401 {object} object "{\"message\":\"string\"}"
+// @Failure 408 {object} object "{\"error\":\"string\"}"
+// @Failure 500 {object} object "{\"error\":\"string\"}"
+// @Router /users/{id}/calendar.ics [get]
+func(uh *UsersHandler) GetCalendar(c *fiber.Ctx) error{
+	ctx, cancel := context.WithTimeout(c.Context(), time.Second*5)
+	defer cancel()
+	eH := errh.NewErrorHander(c, uh.Logger, "get-calendar")
+	feed,err:=uh.UserService.GetCalendar(ctx,c.Params("id"),c.Query("token"))
+	if err!=nil{
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errh.RequestTimedOut(eH, err)
+		}
+		if errors.Is(err, services.ErrInvalidCalendarToken) {
+			c.Status(fiber.StatusUnauthorized)
+			return c.JSON(fiber.Map{
+				"message": "unauthorized",
+			})
+		}
+		c.Status(fiber.StatusInternalServerError)
+		return c.JSON(fiber.Map{
+			"error": "failed to get calendar: " + err.Error(),
+		})
+	}
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	return c.Send(feed)
+}
+
+func calendarUrl(c *fiber.Ctx, id, token string) string {
+	return fmt.Sprintf("%s/api/users/%s/calendar.ics?token=%s", c.BaseURL(), id, token)
 }
\ No newline at end of file
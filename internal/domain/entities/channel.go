@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"github.com/google/uuid"
+)
+
+// Notification types a user can route to their own set of channels.
+const (
+	NotificationReminder = "reminder"
+	NotificationStart    = "start"
+	NotificationEvent    = "event"
+	NotificationWaitlist = "waitlist"
+	NotificationLfg      = "lfg"
+	NotificationFriend   = "friend"
+	NotificationComment  = "comment"
+)
+
+// NotificationDigest is the summary of the batched notifications. It is not
+// routed, it goes to the channels the batched ones were meant for.
+const NotificationDigest = "digest"
+
+var NotificationTypes = []string{NotificationReminder, NotificationStart, NotificationEvent, NotificationWaitlist, NotificationLfg, NotificationFriend, NotificationComment}
+
+// ChannelPreference lists the channels, besides the in-app notification,
+// that deliver one notification type to the user.
+type ChannelPreference struct {
+	Type     string   `json:"type"`
+	Channels []string `json:"channels"`
+}
+
+// Contact is where a channel reaches the user: a chat id, a mailbox or a
+// webhook url. Secret signs the webhook payloads. Language is the one the
+// message was rendered in, for the texts a channel adds itself.
+type Contact struct {
+	UserId   uuid.UUID `json:"user_id"`
+	Channel  string    `json:"channel"`
+	Address  string    `json:"address"`
+	Secret   string    `json:"-"`
+	Language string    `json:"-"`
+}
@@ -2,6 +2,7 @@ package entities
 
 import (
 	"github.com/google/uuid"
+	"slices"
 	"time"
 )
 
@@ -11,6 +12,75 @@ type Event struct {
 	Body        string         `json:"body"`
 	Game        string         `json:"game"`
 	Max         int            `json:"max"`
-	Time        time.Time      `json:"minute"`
-	NotificatedPre bool		`json:"notificated_pre"`
-}
\ No newline at end of file
+	Time        time.Time      `json:"time"`
+	TimeZone    string         `json:"time_zone"`
+	Recurrence  *Recurrence    `json:"recurrence,omitempty"`
+	SeriesId    uuid.UUID      `json:"series_id"`
+	Occurrence  int            `json:"occurrence"`
+	State       string         `json:"state"`
+	EndTime     time.Time      `json:"end_time"`
+	Visibility  string         `json:"visibility"`
+	InviteCode  string         `json:"invite_code,omitempty"`
+	Roles       []RoleSlot     `json:"roles,omitempty"`
+	MinReliability float64     `json:"min_reliability"`
+	// Sequence grows on every change so calendar clients replace their copy.
+	Sequence    int            `json:"sequence"`
+}
+
+// RoleSlot is a named part of the team composition with its own capacity.
+type RoleSlot struct {
+	Name  string `json:"name"`
+	Slots int    `json:"slots"`
+}
+
+const (
+	EventScheduled  = "scheduled"
+	EventStarting   = "starting"
+	EventInProgress = "in_progress"
+	EventFinished   = "finished"
+	EventCancelled  = "cancelled"
+)
+
+const (
+	VisibilityPublic  = "public"
+	VisibilityFriends = "friends"
+	VisibilityInvite  = "invite"
+)
+
+// ActiveEventStates are the states of events that are not archived yet.
+var ActiveEventStates = []string{EventScheduled, EventStarting, EventInProgress}
+
+var eventTransitions = map[string][]string{
+	EventScheduled:  {EventStarting, EventInProgress, EventCancelled},
+	EventStarting:   {EventInProgress, EventCancelled},
+	EventInProgress: {EventFinished},
+}
+
+func (e *Event) CanTransition(to string) bool {
+	return slices.Contains(eventTransitions[e.State], to)
+}
+
+// Open reports whether players can still join the event, i.e. it has not
+// started, finished or been cancelled.
+func (e *Event) Open() bool {
+	return e.State == EventScheduled || e.State == EventStarting
+}
+
+// Role returns the slot with the given name.
+func (e *Event) Role(name string) (RoleSlot, bool) {
+	for _, role := range e.Roles {
+		if role.Name == name {
+			return role, true
+		}
+	}
+	return RoleSlot{}, false
+}
+
+// Location returns the IANA zone the event was planned in, falling back to UTC.
+func (e *Event) Location() *time.Location {
+	loc, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
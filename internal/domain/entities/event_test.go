@@ -0,0 +1,19 @@
+package entities
+
+import "testing"
+
+func TestEventOpen(t *testing.T) {
+	tests := map[string]bool{
+		EventScheduled:  true,
+		EventStarting:   true,
+		EventInProgress: false,
+		EventFinished:   false,
+		EventCancelled:  false,
+	}
+	for state, want := range tests {
+		e := Event{State: state}
+		if got := e.Open(); got != want {
+			t.Errorf("Open() in state %q = %v, want %v", state, got, want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LfgTicket is a player waiting in the looking-for-group queue.
+type LfgTicket struct {
+	UserId    uuid.UUID `json:"user_id"`
+	Game      string    `json:"game"`
+	PartySize int       `json:"party_size"`
+	MinRating float64   `json:"min_rating"`
+	MaxRating float64   `json:"max_rating"`
+	Rating    float64   `json:"rating"`
+	Time      time.Time `json:"time"`
+}
+
+// Accepts reports whether a player with the given rating fits the range the
+// ticket asked for. A zero MaxRating means there is no upper bound.
+func (t *LfgTicket) Accepts(rating float64) bool {
+	return rating >= t.MinRating && (t.MaxRating == 0 || rating <= t.MaxRating)
+}
+
+// Matches reports whether both players can end up in the same group.
+func (t *LfgTicket) Matches(other LfgTicket) bool {
+	return t.Game == other.Game && t.PartySize == other.PartySize && t.Accepts(other.Rating) && other.Accepts(t.Rating)
+}
@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"time"
+)
+
+// Message is a text of the locale catalog with the values of its
+// placeholders. It is stored as is and rendered for every reader in their
+// own language and time zone.
+type Message struct {
+	Key    string        `json:"key"`
+	Params MessageParams `json:"params"`
+}
+
+// MessageParams are the values a message template can use. Changes names the
+// edited fields of an event, each is rendered with its new value. Login is
+// the user who caused the message, Items are the rendered lines of a digest.
+type MessageParams struct {
+	Title          string        `json:"title,omitempty"`
+	Game           string        `json:"game,omitempty"`
+	Time           time.Time     `json:"time,omitempty"`
+	Link           string        `json:"link,omitempty"`
+	Lead           time.Duration `json:"lead,omitempty"`
+	Max            int           `json:"max,omitempty"`
+	MinReliability float64       `json:"min_reliability,omitempty"`
+	Reason         string        `json:"reason,omitempty"`
+	Changes        []string      `json:"changes,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Login          string        `json:"login,omitempty"`
+	Count          int           `json:"count,omitempty"`
+	Items          []string      `json:"items,omitempty"`
+}
+
+// Event fields a change message can name.
+const (
+	ChangeBody           = "body"
+	ChangeGame           = "game"
+	ChangeMax            = "max"
+	ChangeMinReliability = "min_reliability"
+	ChangeTime           = "time"
+)
@@ -11,4 +11,9 @@ type Notification struct {
 	EventId uuid.UUID `json:"event_id"`
 	Body    string    `json:"body"`
 	Time time.Time `json:"time"`
-}
\ No newline at end of file
+	// ReadAt is when the user marked it read, nil while unread.
+	ReadAt *time.Time `json:"read_at"`
+	// Message is what Body is rendered from for each reader, nil for
+	// notifications written before the catalog.
+	Message *Message `json:"-"`
+}
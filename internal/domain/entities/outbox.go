@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ChannelNotification = "notification"
+	ChannelTelegram     = "telegram"
+	ChannelEmail        = "email"
+	ChannelWebhook      = "webhook"
+)
+
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead"
+)
+
+// OutboxPayload is what a channel needs to deliver a message. Without UserId
+// the message goes to every member of the event. Body is the message in the
+// default language, channels render Message again for the recipient.
+type OutboxPayload struct {
+	Type    string    `json:"type,omitempty"`
+	EventId uuid.UUID `json:"event_id"`
+	UserId  string    `json:"user_id,omitempty"`
+	Body    string    `json:"body"`
+	Message *Message  `json:"message,omitempty"`
+	CheckIn bool      `json:"check_in,omitempty"`
+}
+
+// OutboxMessage is a delivery written in the same transaction as the change
+// that caused it. Key makes enqueueing the same delivery twice a no-op.
+type OutboxMessage struct {
+	Id            uuid.UUID     `json:"id"`
+	Key           string        `json:"key"`
+	Channel       string        `json:"channel"`
+	Payload       OutboxPayload `json:"payload"`
+	Status        string        `json:"status"`
+	Attempts      int           `json:"attempts"`
+	NextAttemptAt time.Time     `json:"next_attempt_at"`
+	LastError     string        `json:"last_error"`
+	CreatedAt     time.Time     `json:"created_at"`
+	DeliveredAt   *time.Time    `json:"delivered_at"`
+}
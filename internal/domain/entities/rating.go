@@ -0,0 +1,17 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Rating struct {
+	Id      uuid.UUID `json:"rating_id"`
+	RaterId uuid.UUID `json:"rater_id"`
+	RateeId uuid.UUID `json:"ratee_id"`
+	EventId uuid.UUID `json:"event_id"`
+	Stars   int       `json:"stars"`
+	Comment string    `json:"comment"`
+	Time    time.Time `json:"time"`
+}
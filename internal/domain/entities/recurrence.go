@@ -0,0 +1,88 @@
+package entities
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	FreqDaily  = "daily"
+	FreqWeekly = "weekly"
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence is a reduced RRULE: daily or weekly on given weekdays,
+// bounded by an until date or an occurrence count.
+type Recurrence struct {
+	Freq       string      `json:"freq"`
+	Interval   int         `json:"interval"`
+	Weekdays   []string    `json:"weekdays,omitempty"`
+	Until      *time.Time  `json:"until,omitempty"`
+	Count      int         `json:"count,omitempty"`
+	Exceptions []time.Time `json:"exceptions,omitempty"`
+}
+
+// Next returns the occurrence that follows current together with its index
+// in the series. Occurrences are computed on the wall clock of loc so the
+// start hour survives daylight saving changes.
+func (r *Recurrence) Next(current time.Time, occurrence int, loc *time.Location) (time.Time, int, bool) {
+	interval := max(r.Interval, 1)
+	local := current.In(loc)
+	weekdays := []time.Weekday{}
+	for _, code := range r.Weekdays {
+		weekdays = append(weekdays, weekdayCodes[code])
+	}
+	if len(weekdays) == 0 {
+		weekdays = append(weekdays, local.Weekday())
+	}
+	for day := 1; day <= 366*interval; day++ {
+		candidate := time.Date(local.Year(), local.Month(), local.Day()+day, local.Hour(), local.Minute(), local.Second(), 0, loc)
+		switch r.Freq {
+		case FreqDaily:
+			if day%interval != 0 {
+				continue
+			}
+		case FreqWeekly:
+			if !slices.Contains(weekdays, candidate.Weekday()) || weeksBetween(local, candidate)%interval != 0 {
+				continue
+			}
+		default:
+			return time.Time{}, 0, false
+		}
+		occurrence++
+		if r.Count > 0 && occurrence > r.Count {
+			return time.Time{}, 0, false
+		}
+		if r.Until != nil && candidate.After(*r.Until) {
+			return time.Time{}, 0, false
+		}
+		if r.IsException(candidate) {
+			continue
+		}
+		return candidate, occurrence, true
+	}
+	return time.Time{}, 0, false
+}
+
+func (r *Recurrence) IsException(t time.Time) bool {
+	return slices.ContainsFunc(r.Exceptions, func(e time.Time) bool {
+		return e.Equal(t)
+	})
+}
+
+func weeksBetween(from, to time.Time) int {
+	monday := func(t time.Time) time.Time {
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+	}
+	return int(monday(to).Sub(monday(from)).Hours()/24) / 7
+}
@@ -0,0 +1,13 @@
+package entities
+
+import (
+	"github.com/google/uuid"
+)
+
+// Reminder is a pending notice for one member of an upcoming event. Offsets
+// holds every lead time in minutes that is already due but not sent yet.
+type Reminder struct {
+	UserId  uuid.UUID
+	Event   Event
+	Offsets []int
+}
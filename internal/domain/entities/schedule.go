@@ -0,0 +1,108 @@
+package entities
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Digest modes of a schedule.
+const (
+	DigestOff    = "off"
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+)
+
+// DigestTypes are the notification types that are not urgent and can wait
+// for the digest.
+var DigestTypes = []string{NotificationFriend, NotificationComment}
+
+// NotificationSchedule is when the outside channels may ping the user. Times
+// are "15:04" in the zone of the user. During the quiet hours deliveries wait
+// for their end, a window may span midnight. With a digest mode the
+// notifications of DigestTypes are batched and sent hourly or daily at
+// DigestAt. RemindersInQuiet lets event reminders and starts through the
+// quiet hours.
+type NotificationSchedule struct {
+	UserId           uuid.UUID `json:"-"`
+	QuietFrom        string    `json:"quiet_from"`
+	QuietTo          string    `json:"quiet_to"`
+	Digest           string    `json:"digest"`
+	DigestAt         string    `json:"digest_at"`
+	RemindersInQuiet bool      `json:"reminders_in_quiet"`
+}
+
+// Digests reports whether notifications of the kind wait for the digest.
+func (s *NotificationSchedule) Digests(kind string) bool {
+	return s.Digest != "" && s.Digest != DigestOff && slices.Contains(DigestTypes, kind)
+}
+
+// Holds reports whether a notification of the kind must wait at t, and until
+// when.
+func (s *NotificationSchedule) Holds(kind string, t time.Time, loc *time.Location) (time.Time, bool) {
+	if s.RemindersInQuiet && (kind == NotificationReminder || kind == NotificationStart) {
+		return time.Time{}, false
+	}
+	from, ok1 := clock(s.QuietFrom)
+	to, ok2 := clock(s.QuietTo)
+	if !ok1 || !ok2 || from == to {
+		return time.Time{}, false
+	}
+	t = t.In(loc)
+	now := t.Hour()*60 + t.Minute()
+	quiet := from <= now && now < to
+	if from > to {
+		quiet = now >= from || now < to
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+	return at(t, to, loc), true
+}
+
+// NextDigest returns when the digest holding a notification queued at t is
+// due: the next full hour or the next DigestAt.
+func (s *NotificationSchedule) NextDigest(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	if s.Digest != DigestDaily {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+	}
+	minutes, _ := clock(s.DigestAt)
+	return at(t, minutes, loc)
+}
+
+// at returns the first moment after t when the clock in loc shows the
+// minutes of the day.
+func at(t time.Time, minutes int, loc *time.Location) time.Time {
+	next := time.Date(t.Year(), t.Month(), t.Day(), minutes/60, minutes%60, 0, 0, loc)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// clock parses "15:04" into minutes of the day.
+func clock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// DigestItem is a notification waiting for the digest of one channel.
+type DigestItem struct {
+	Id        uuid.UUID     `json:"id"`
+	UserId    uuid.UUID     `json:"user_id"`
+	Channel   string        `json:"channel"`
+	Payload   OutboxPayload `json:"payload"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// PendingDigest is a channel of a user with items waiting since Since.
+type PendingDigest struct {
+	UserId  uuid.UUID
+	Channel string
+	Since   time.Time
+}
@@ -0,0 +1,17 @@
+package entities
+
+const (
+	SearchEvent = "event"
+	SearchGame  = "game"
+	SearchNews  = "news"
+	SearchUser  = "user"
+)
+
+// SearchHit is a single search result, Type tells which entity Id points to.
+type SearchHit struct {
+	Type    string  `json:"type"`
+	Id      string  `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
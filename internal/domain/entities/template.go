@@ -0,0 +1,21 @@
+package entities
+
+import (
+	"github.com/google/uuid"
+)
+
+// EventTemplate keeps the reusable part of an event, everything except its time.
+type EventTemplate struct {
+	Id             uuid.UUID  `json:"template_id"`
+	AuthorId       uuid.UUID  `json:"author_id"`
+	Name           string     `json:"name"`
+	Body           string     `json:"body"`
+	Game           string     `json:"game"`
+	Max            int        `json:"max"`
+	Duration       int        `json:"duration"`
+	TimeZone       string     `json:"time_zone"`
+	Roles          []RoleSlot `json:"roles,omitempty"`
+	AuthorRole     string     `json:"author_role,omitempty"`
+	Visibility     string     `json:"visibility"`
+	MinReliability float64    `json:"min_reliability"`
+}
@@ -19,4 +19,25 @@ type User struct {
 	Avatar          string		`json:"avatar"`
 	Discord         string		`json:"discord"`
 	DateOfRegister 	time.Time  `json:"date_of_register"`
+	TimeZone        string         `json:"time_zone"`
+	Reliability     float64        `json:"reliability"`
+	Language        string         `json:"language"`
+	// TelegramLinked tells the profile whether a chat is bound, it is set
+	// by the service.
+	TelegramLinked  bool           `json:"telegram_linked"`
+}
+
+// HasTelegram reports whether a Telegram chat is bound to the user. Rows
+// from before the chat_id migration held 'unknown' for none.
+func (u *User) HasTelegram() bool {
+	return u.ChatId != "" && u.ChatId != "unknown"
+}
+
+// Location returns the user's stored IANA zone, falling back to UTC.
+func (u *User) Location() *time.Location {
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
\ No newline at end of file
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ChannelRepository interface {
+	FetchPreferences(ctx context.Context, user_id string) ([]entities.ChannelPreference, error)
+	FetchChannels(ctx context.Context, user_id, kind string) ([]string, bool, error)
+	SavePreference(ctx context.Context, user_id string, preference entities.ChannelPreference) error
+	FindContact(ctx context.Context, user_id, channel string) (*entities.Contact, error)
+	SaveContact(ctx context.Context, contact entities.Contact) error
+	DeleteContact(ctx context.Context, user_id, channel string) error
+	FetchSchedule(ctx context.Context, user_id string) (*entities.NotificationSchedule, error)
+	SaveSchedule(ctx context.Context, schedule entities.NotificationSchedule) error
+}
+
+type channelRepository struct {
+	DB Querier
+}
+
+func NewChannelRepository(db *pgxpool.Pool) ChannelRepository {
+	return &channelRepository{
+		DB: NewQuerier(db),
+	}
+}
+
+func (cr *channelRepository) FetchPreferences(ctx context.Context, user_id string) ([]entities.ChannelPreference, error){
+	preferences:=[]entities.ChannelPreference{}
+	rows,err:=cr.DB.Query(ctx,"SELECT type,channels FROM notification_preferences WHERE user_id = $1 ORDER BY type",user_id)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		p:=entities.ChannelPreference{}
+		if err:=rows.Scan(&p.Type,&p.Channels);err!=nil{
+			return nil,err
+		}
+		preferences=append(preferences,p)
+	}
+	if err:=rows.Err();err!=nil{
+		return nil,err
+	}
+	return preferences,nil
+}
+
+// FetchChannels returns the channels the user picked for the notification
+// type. It reports false when the user never chose any.
+func (cr *channelRepository) FetchChannels(ctx context.Context, user_id, kind string) ([]string, bool, error){
+	channels:=[]string{}
+	if err:=cr.DB.QueryRow(ctx,"SELECT channels FROM notification_preferences WHERE user_id = $1 AND type = $2",user_id,kind).Scan(&channels);err!=nil{
+		if errors.Is(err, pgx.ErrNoRows){
+			return nil,false,nil
+		}
+		return nil,false,err
+	}
+	return channels,true,nil
+}
+
+func (cr *channelRepository) SavePreference(ctx context.Context, user_id string, preference entities.ChannelPreference) error{
+	if _,err:=cr.DB.Exec(ctx,"INSERT INTO notification_preferences (user_id,type,channels) VALUES ($1,$2,$3) ON CONFLICT (user_id,type) DO UPDATE SET channels = EXCLUDED.channels",
+	user_id,preference.Type,preference.Channels);err!=nil{
+		return err
+	}
+	return nil
+}
+
+// FindContact returns nil when the user has no address for the channel.
+func (cr *channelRepository) FindContact(ctx context.Context, user_id, channel string) (*entities.Contact, error){
+	contact:=entities.Contact{}
+	if err:=cr.DB.QueryRow(ctx,"SELECT user_id,channel,address,secret FROM notification_contacts WHERE user_id = $1 AND channel = $2",user_id,channel).Scan(
+		&contact.UserId,&contact.Channel,&contact.Address,&contact.Secret);err!=nil{
+		if errors.Is(err, pgx.ErrNoRows){
+			return nil,nil
+		}
+		return nil,err
+	}
+	return &contact,nil
+}
+
+func (cr *channelRepository) SaveContact(ctx context.Context, contact entities.Contact) error{
+	if _,err:=cr.DB.Exec(ctx,"INSERT INTO notification_contacts (user_id,channel,address,secret) VALUES ($1,$2,$3,$4) ON CONFLICT (user_id,channel) DO UPDATE SET address = EXCLUDED.address, secret = EXCLUDED.secret",
+	contact.UserId,contact.Channel,contact.Address,contact.Secret);err!=nil{
+		return err
+	}
+	return nil
+}
+
+func (cr *channelRepository) DeleteContact(ctx context.Context, user_id, channel string) error{
+	if _,err:=cr.DB.Exec(ctx,"DELETE FROM notification_contacts WHERE user_id = $1 AND channel = $2",user_id,channel);err!=nil{
+		return err
+	}
+	return nil
+}
+
+// FetchSchedule returns the schedule of the user, without quiet hours and
+// digest when none was saved.
+func (cr *channelRepository) FetchSchedule(ctx context.Context, user_id string) (*entities.NotificationSchedule, error){
+	schedule:=entities.NotificationSchedule{Digest: entities.DigestOff}
+	if err:=cr.DB.QueryRow(ctx,"SELECT user_id,quiet_from,quiet_to,digest,digest_at,reminders_in_quiet FROM notification_schedules WHERE user_id = $1",user_id).Scan(
+		&schedule.UserId,&schedule.QuietFrom,&schedule.QuietTo,&schedule.Digest,&schedule.DigestAt,&schedule.RemindersInQuiet);err!=nil{
+		if errors.Is(err, pgx.ErrNoRows){
+			return &schedule,nil
+		}
+		return nil,err
+	}
+	return &schedule,nil
+}
+
+func (cr *channelRepository) SaveSchedule(ctx context.Context, schedule entities.NotificationSchedule) error{
+	if _,err:=cr.DB.Exec(ctx,`INSERT INTO notification_schedules (user_id,quiet_from,quiet_to,digest,digest_at,reminders_in_quiet) VALUES ($1,$2,$3,$4,$5,$6)
+		ON CONFLICT (user_id) DO UPDATE SET quiet_from = EXCLUDED.quiet_from, quiet_to = EXCLUDED.quiet_to, digest = EXCLUDED.digest, digest_at = EXCLUDED.digest_at, reminders_in_quiet = EXCLUDED.reminders_in_quiet`,
+	schedule.UserId,schedule.QuietFrom,schedule.QuietTo,schedule.Digest,schedule.DigestAt,schedule.RemindersInQuiet);err!=nil{
+		return err
+	}
+	return nil
+}
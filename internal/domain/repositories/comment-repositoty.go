@@ -3,27 +3,33 @@ package repositories
 import (
 	"context"
 	"crap/internal/domain/entities"
-	"github.com/jackc/pgx/v5"
+	"crap/internal/infrastructure/db/query"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type CommentRepository interface {
 	Create(ctx context.Context, comment entities.Comment) error
-	FetchFromUser(ctx context.Context,id string, amount, page int) ([]entities.Comment, error)
-	FetchFromEvent(ctx context.Context, id string, amount, page int) ([]entities.Comment, error)
-	FetchFromNews(ctx context.Context,id string, amount, page int) ([]entities.Comment, error)
+	FetchFromUser(ctx context.Context,id string, w query.Window) (*query.Page[entities.Comment], error)
+	FetchFromEvent(ctx context.Context, id string, w query.Window) (*query.Page[entities.Comment], error)
+	FetchFromNews(ctx context.Context,id string, w query.Window) (*query.Page[entities.Comment], error)
 	AddToUser(ctx context.Context,id, cid string) error
 	AddToNews(ctx context.Context,id, cid string) error
 	AddToEvent(ctx context.Context,id, cid string) error
 }
 
 
+// commentKey lists comments from the oldest, as a conversation reads.
+var commentKey = query.Key{Column: "c.time", Type: "timestamptz", Id: "c.id", IdType: "uuid"}
+
 type commentRepository struct {
-	DB *pgx.Conn
+	DB Querier
 }
 
-func NewCommentRepository(db *pgx.Conn) CommentRepository {
+func NewCommentRepository(db *pgxpool.Pool) CommentRepository {
 	return &commentRepository{
-		DB: db,
+		DB: NewQuerier(db),
 	}
 }
 
@@ -34,39 +40,16 @@ func (cr *commentRepository) Create(ctx context.Context, comment entities.Commen
 	return nil	
 }
 
-func (cr *commentRepository) Fetch(ctx context.Context,whose, id string, amount, page int) ([]entities.Comment, error) {
+// fetch lists the comments attached through the given link table.
+func (cr *commentRepository) fetch(ctx context.Context, link, column, id string, w query.Window) (*query.Page[entities.Comment], error) {
 	comments := []entities.Comment{}
-	var query string
-	switch whose{
-	case "user":
-		query = "SELECT * FROM comments c JOIN users_comments uc ON c.id=uc.comment_id WHERE uc.user_id = $1 ORDER BY time OFFSET $2 LIMIT $3"
-	case "event":
-		query = "SELECT * FROM comments c JOIN events_comments ec ON c.id=ec.comment_id WHERE ec.event_id = $1 ORDER BY time OFFSET $2 LIMIT $3"
-	case "news":
-		query = "SELECT * FROM comments c JOIN news_comments uc ON c.id=nc.comment_id WHERE nc.news_id = $1 ORDER BY time OFFSET $2 LIMIT $3"
-	}
-	rows,err:=cr.DB.Query(ctx,query,id,page*amount-amount, amount)
+	b := query.Select("c.id,c.author_id,c.body,c.time","comments c JOIN "+link+" l ON c.id = l.comment_id",nil)
+	b.Where("l."+column+" = "+b.Arg(id))
+	sql,args,err:=b.Keyset(commentKey,w).Build()
 	if err!=nil{
 		return nil,err
 	}
-	defer rows.Close()
-	for rows.Next(){
-		comment:=entities.Comment{}
-		if err:=rows.Scan(&comment.Id,&comment.AuthorId,&comment.Body,&comment.Time);err!=nil{
-			return nil,err
-		}
-		comments=append(comments, comment)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return comments, nil
-}
-
-func (cr *commentRepository) FetchFromUser(ctx context.Context,id string, amount, page int) ([]entities.Comment, error){
-	comments := []entities.Comment{}
-	query := "SELECT * FROM comments c JOIN users_comments uc ON c.id=uc.comment_id WHERE uc.user_id = $1 OFFSET $2 LIMIT $3"
-	rows,err:=cr.DB.Query(ctx,query,id,page*amount-amount, amount)
+	rows,err:=cr.DB.Query(ctx,sql,args...)
 	if err!=nil{
 		return nil,err
 	}
@@ -81,49 +64,22 @@ func (cr *commentRepository) FetchFromUser(ctx context.Context,id string, amount
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return comments, nil
+	page:=query.Paginate(comments,commentKey,w,func(c entities.Comment) query.Cursor {
+		return query.Cursor{Value: c.Time.Format(time.RFC3339Nano), Id: c.Id.String()}
+	})
+	return &page, nil
 }
 
-func (cr *commentRepository) FetchFromEvent(ctx context.Context, id string, amount, page int) ([]entities.Comment, error){
-	comments := []entities.Comment{}
-	query := "SELECT * FROM comments c JOIN events_comments ec ON c.id=ec.comment_id WHERE ec.event_id = $1 OFFSET $2 LIMIT $3"
-	rows,err:=cr.DB.Query(ctx,query,id,page*amount-amount, amount)
-	if err!=nil{
-		return nil,err
-	}
-	defer rows.Close()
-	for rows.Next(){
-		comment:=entities.Comment{}
-		if err:=rows.Scan(&comment.Id,&comment.AuthorId,&comment.Body,&comment.Time);err!=nil{
-			return nil,err
-		}
-		comments=append(comments, comment)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return comments, nil
+func (cr *commentRepository) FetchFromUser(ctx context.Context,id string, w query.Window) (*query.Page[entities.Comment], error){
+	return cr.fetch(ctx,"users_comments","user_id",id,w)
 }
 
-func (cr *commentRepository) FetchFromNews(ctx context.Context,id string, amount, page int) ([]entities.Comment, error){
-	comments := []entities.Comment{}
-	query := "SELECT * FROM comments c JOIN news_comments nc ON c.id=nc.comment_id WHERE nc.news_id = $1 OFFSET $2 LIMIT $3"
-	rows,err:=cr.DB.Query(ctx,query,id,page*amount-amount, amount)
-	if err!=nil{
-		return nil,err
-	}
-	defer rows.Close()
-	for rows.Next(){
-		comment:=entities.Comment{}
-		if err:=rows.Scan(&comment.Id,&comment.AuthorId,&comment.Body,&comment.Time);err!=nil{
-			return nil,err
-		}
-		comments=append(comments, comment)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return comments, nil
+func (cr *commentRepository) FetchFromEvent(ctx context.Context, id string, w query.Window) (*query.Page[entities.Comment], error){
+	return cr.fetch(ctx,"events_comments","event_id",id,w)
+}
+
+func (cr *commentRepository) FetchFromNews(ctx context.Context,id string, w query.Window) (*query.Page[entities.Comment], error){
+	return cr.fetch(ctx,"news_comments","news_id",id,w)
 }
 
 func (cr *commentRepository) AddToUser(ctx context.Context,id, cid string) error{
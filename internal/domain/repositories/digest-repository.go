@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DigestRepository interface {
+	Add(ctx context.Context, item entities.DigestItem) error
+	Pending(ctx context.Context) ([]entities.PendingDigest, error)
+	Take(ctx context.Context, user_id, channel string) ([]entities.DigestItem, error)
+}
+
+type digestRepository struct {
+	DB Querier
+}
+
+func NewDigestRepository(db *pgxpool.Pool) DigestRepository {
+	return &digestRepository{
+		DB: NewQuerier(db),
+	}
+}
+
+// Add keeps the item for the next digest. The item takes the id of its outbox
+// message, so a repeated delivery adds nothing.
+func (dr *digestRepository) Add(ctx context.Context, item entities.DigestItem) error{
+	if _,err:=dr.DB.Exec(ctx,"INSERT INTO notification_digests (id,user_id,channel,payload,created_at) VALUES ($1,$2,$3,$4,$5) ON CONFLICT (id) DO NOTHING",
+	item.Id,item.UserId,item.Channel,item.Payload,item.CreatedAt);err!=nil{
+		return err
+	}
+	return nil
+}
+
+// Pending lists every channel of a user with waiting items and the time of
+// the oldest one.
+func (dr *digestRepository) Pending(ctx context.Context) ([]entities.PendingDigest, error){
+	rows,err:=dr.DB.Query(ctx,"SELECT user_id,channel,min(created_at) FROM notification_digests GROUP BY user_id,channel")
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	pending:=[]entities.PendingDigest{}
+	for rows.Next(){
+		p:=entities.PendingDigest{}
+		if err:=rows.Scan(&p.UserId,&p.Channel,&p.Since);err!=nil{
+			return nil,err
+		}
+		pending=append(pending,p)
+	}
+	if err:=rows.Err();err!=nil{
+		return nil,err
+	}
+	return pending,nil
+}
+
+// Take removes and returns the items of the channel, oldest first. It is
+// meant to run in the transaction that queues the digest, so concurrent
+// schedulers do not send the same items.
+func (dr *digestRepository) Take(ctx context.Context, user_id, channel string) ([]entities.DigestItem, error){
+	rows,err:=dr.DB.Query(ctx,"DELETE FROM notification_digests WHERE user_id = $1 AND channel = $2 RETURNING id,user_id,channel,payload,created_at",user_id,channel)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	items:=[]entities.DigestItem{}
+	for rows.Next(){
+		item:=entities.DigestItem{}
+		if err:=rows.Scan(&item.Id,&item.UserId,&item.Channel,&item.Payload,&item.CreatedAt);err!=nil{
+			return nil,err
+		}
+		items=append(items,item)
+	}
+	if err:=rows.Err();err!=nil{
+		return nil,err
+	}
+	slices.SortFunc(items,func(a, b entities.DigestItem) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return items,nil
+}
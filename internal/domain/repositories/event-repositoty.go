@@ -3,45 +3,108 @@ package repositories
 import (
 	"context"
 	"crap/internal/domain/entities"
+	"crap/internal/infrastructure/db/query"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
 type EventRepository interface{
-	Create(ctx context.Context, event entities.Event) error
+	Create(ctx context.Context, event entities.Event, authorRole string) error
 	Delete(ctx context.Context, event entities.Event) error
 	FindById(ctx context.Context, id string) (*entities.Event, error)
 	FetchUpcoming(ctx context.Context, time time.Time) ([]entities.Event, error)
-	Fetch(ctx context.Context, amount, page int) ([]entities.Event, error)
-	Join(ctx context.Context,user_id,event_id string) error 
+	FetchEnded(ctx context.Context, time time.Time) ([]entities.Event, error)
+	Fetch(ctx context.Context, states []string, callerId string, w query.Window) (*query.Page[entities.Event], error)
+	FindByInviteCode(ctx context.Context, code string) (*entities.Event, error)
+	UpdateState(ctx context.Context, id, from, to string) (bool, error)
+	CountConflicts(ctx context.Context, id string, start, end time.Time) (int, error)
+	Join(ctx context.Context,user_id,event_id,role string) error
 	Unjoin(ctx context.Context,user_id,event_id string) error
 	FetchMembers(ctx context.Context,id string) ([]string,error)
+	FetchJoined(ctx context.Context, user_id string) ([]entities.Event, error)
+	FetchMemberRoles(ctx context.Context,id string) (map[string]string,error)
+	CountRoleMembers(ctx context.Context, id string) (map[string]int, error)
+	CheckIn(ctx context.Context, user_id, event_id string, at time.Time) (bool, error)
+	CloseAttendance(ctx context.Context, before time.Time) ([]string, error)
+	LockById(ctx context.Context, id string) (*entities.Event, error)
+	CountMembers(ctx context.Context, id string) (int, error)
+	IsMember(ctx context.Context, user_id, event_id string) (bool, error)
+	AddToWaitlist(ctx context.Context, user_id, event_id, role string) error
+	RemoveFromWaitlist(ctx context.Context, user_id, event_id string) (bool, error)
+	WaitlistPosition(ctx context.Context, user_id, event_id string) (int, error)
+	PopWaitlist(ctx context.Context, event_id string, roles []string) (string, string, error)
 	Save(c context.Context, event entities.Event) error
-	Filter(ctx context.Context, game,max,time string, amount, page int) ([]entities.Event, error)
-	Sort(ctx context.Context, field,dir string, amount, page int) ([]entities.Event, error)
+	Filter(ctx context.Context, filter EventFilter, callerId string, amount, page int) ([]entities.Event, error)
+	Sort(ctx context.Context, orders []query.Order, callerId string, amount, page int) ([]entities.Event, error)
+}
+
+// EventFilter holds the optional conditions of Filter, zero values are skipped.
+type EventFilter struct {
+	Games  []string
+	Max    int
+	MinMax int
+	Time   time.Time
+	From   time.Time
+	To     time.Time
+	Role   string
+}
+
+var eventFilterColumns = query.Columns{"game": "game", "max": "max", "time": "time", "state": "state"}
+
+var eventSortColumns = query.Columns{"max": "max", "time": "time"}
+
+// eventKey lists events from the earliest start.
+var eventKey = query.Key{Column: "time", Type: "timestamptz", Id: "id", IdType: "uuid"}
+
+func eventPosition(e entities.Event) query.Cursor {
+	return query.Cursor{Value: e.Time.Format(time.RFC3339Nano), Id: e.Id.String()}
+}
+
+const eventColumns = "id,author_id,body,game,max,time,time_zone,recurrence,series_id,occurrence,state,end_time,visibility,invite_code,roles,min_reliability,sequence"
+
+// eventFields lists the scan destinations in the order of eventColumns.
+func eventFields(event *entities.Event) []any {
+	return []any{&event.Id,&event.AuthorId,&event.Body,&event.Game,&event.Max,&event.Time,&event.TimeZone,&event.Recurrence,&event.SeriesId,&event.Occurrence,&event.State,&event.EndTime,&event.Visibility,&event.InviteCode,&event.Roles,&event.MinReliability,&event.Sequence}
+}
+
+func scanEvent(row pgx.Row, event *entities.Event) error {
+	return row.Scan(eventFields(event)...)
+}
+
+// visibleTo builds the condition that keeps only events the user behind the
+// given placeholder may see: public ones, own ones, joined ones and those of
+// friends when they are friends-only.
+func visibleTo(param string) string {
+	return fmt.Sprintf(`(visibility = '%[2]s' OR author_id::text = %[1]s
+		OR EXISTS (SELECT 1 FROM users_events ue WHERE ue.event_id = events.id AND ue.user_id::text = %[1]s)
+		OR (visibility = '%[3]s' AND EXISTS (SELECT 1 FROM friendships f WHERE f.relation = 'accepted'
+			AND ((f.user_id1 = events.author_id AND f.user_id2::text = %[1]s) OR (f.user_id2 = events.author_id AND f.user_id1::text = %[1]s)))))`,
+		param, entities.VisibilityPublic, entities.VisibilityFriends)
 }
 
 type eventRepository struct {
-	DB    *pgx.Conn
+	DB    Querier
 	Redis *redis.Client
 }
 
-func NewEventRepository(db *pgx.Conn, redis *redis.Client) EventRepository {
+func NewEventRepository(db *pgxpool.Pool, redis *redis.Client) EventRepository {
 	return &eventRepository{
-		DB:    db,
+		DB:    NewQuerier(db),
 		Redis: redis,
 	}
 }
 
-func (er *eventRepository) Create(ctx context.Context, event entities.Event) error {
-	if _,err := er.DB.Exec(ctx, "INSERT INTO events (id,author_id,body,game,max,time,notificated_pre) values($1,$2,$3,$4,$5,$6,$7)", event.Id, event.AuthorId, event.Body, event.Game, event.Max, event.Time, event.NotificatedPre); err != nil {
+func (er *eventRepository) Create(ctx context.Context, event entities.Event, authorRole string) error {
+	if _,err := er.DB.Exec(ctx, "INSERT INTO events (id,author_id,body,game,max,time,time_zone,recurrence,series_id,occurrence,state,end_time,visibility,invite_code,roles,min_reliability) values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)", event.Id, event.AuthorId, event.Body, event.Game, event.Max, event.Time, event.TimeZone, event.Recurrence, event.SeriesId, event.Occurrence, event.State, event.EndTime, event.Visibility, event.InviteCode, event.Roles, event.MinReliability); err != nil {
 		return err
 	}
-	if _,err:=er.DB.Exec(ctx,"INSERT INTO users_events (event_id,user_id) values($1,$2)",event.Id,event.AuthorId);err!=nil{
+	if _,err:=er.DB.Exec(ctx,"INSERT INTO users_events (event_id,user_id,role) values($1,$2,$3)",event.Id,event.AuthorId,authorRole);err!=nil{
 		return err
 	}
 	eventdata, err := json.Marshal(event)
@@ -57,7 +120,7 @@ func (er *eventRepository) Create(ctx context.Context, event entities.Event) err
 }
 
 func (er *eventRepository) Save(ctx context.Context, event entities.Event) error {
-	if _,err := er.DB.Exec(ctx, "UPDATE events SET author_id=$1,body=$2,game=$3,max=$4,time=$5,notificated_pre=$6 WHERE id = $7",event.AuthorId,event.Body, event.Game, event.Max, event.Time, event.NotificatedPre,event.Id); err != nil {
+	if err := er.DB.QueryRow(ctx, "UPDATE events SET author_id=$1,body=$2,game=$3,max=$4,time=$5,time_zone=$6,recurrence=$7,end_time=$8,visibility=$9,invite_code=$10,min_reliability=$11,sequence=sequence+1 WHERE id = $12 RETURNING sequence",event.AuthorId,event.Body, event.Game, event.Max, event.Time,event.TimeZone,event.Recurrence,event.EndTime,event.Visibility,event.InviteCode,event.MinReliability,event.Id).Scan(&event.Sequence); err != nil {
 		return err
 	}
 	if er.Redis != nil {
@@ -69,6 +132,9 @@ func (er *eventRepository) Save(ctx context.Context, event entities.Event) error
 		if err != nil {
 			return err
 		}
+		if ttl <= 0 {
+			return er.Redis.Del(ctx, event.Id.String()).Err()
+		}
 		if err := er.Redis.Set(ctx, event.Id.String(), eventdata, ttl).Err(); err != nil {
 			return err
 		}
@@ -88,14 +154,14 @@ func (er *eventRepository) Delete(ctx context.Context, event entities.Event) err
 
 func (er *eventRepository) FetchUpcoming(ctx context.Context, time time.Time) ([]entities.Event, error) {
 	events := []entities.Event{}
-	rows,err:=er.DB.Query(ctx,"SELECT * FROM events where time <= $1",time)
+	rows,err:=er.DB.Query(ctx,"SELECT "+eventColumns+" FROM events where time <= $1 AND state IN ($2,$3)",time,entities.EventScheduled,entities.EventStarting)
 	if err!=nil{
 		return nil,err
 	}
 	defer rows.Close()
 	for rows.Next(){
 		event:=entities.Event{}
-		if err:=rows.Scan(&event.Id,&event.AuthorId,&event.Body,&event.Game,&event.Max,&event.Time,&event.NotificatedPre);err!=nil{
+		if err:=scanEvent(rows,&event);err!=nil{
 			return nil,err
 		}
 		events=append(events, event)
@@ -109,18 +175,20 @@ func (er *eventRepository) FindById(ctx context.Context, id string) (*entities.E
 		eventdata, err := er.Redis.Get(ctx, id).Result()
 		if err != nil {
 			if err == redis.Nil {
-				if err:=er.DB.QueryRow(ctx,"SELECT * FROM events where id= $1",id).Scan(&event.Id,&event.AuthorId,&event.Body,&event.Game,&event.Max,&event.Time,&event.NotificatedPre);err!=nil{
+				if err:=scanEvent(er.DB.QueryRow(ctx,"SELECT "+eventColumns+" FROM events where id= $1",id),&event);err!=nil{
 					return nil,err
 				}
 				eventdata, err := json.Marshal(event)
 				if err != nil {
 					return nil, err
 				}
-				if err := er.Redis.Set(ctx, id, eventdata, time.Until(event.Time)).Err(); err != nil {
-					return nil, err
+				if ttl := time.Until(event.Time); ttl > 0 {
+					if err := er.Redis.Set(ctx, id, eventdata, ttl).Err(); err != nil {
+						return nil, err
+					}
 				}
 			} else {
-				if err:=er.DB.QueryRow(ctx,"SELECT * FROM events where id= $1",id).Scan(&event.Id,&event.AuthorId,&event.Body,&event.Game,&event.Max,&event.Time,&event.NotificatedPre);err!=nil{
+				if err:=scanEvent(er.DB.QueryRow(ctx,"SELECT "+eventColumns+" FROM events where id= $1",id),&event);err!=nil{
 					return nil,err
 				}
 			}
@@ -130,33 +198,27 @@ func (er *eventRepository) FindById(ctx context.Context, id string) (*entities.E
 			}
 		}
 	} else {
-		if err:=er.DB.QueryRow(ctx,"SELECT * FROM events where id= $1",id).Scan(&event.Id,&event.AuthorId,&event.Body,&event.Game,&event.Max,&event.Time,&event.NotificatedPre);err!=nil{
+		if err:=scanEvent(er.DB.QueryRow(ctx,"SELECT "+eventColumns+" FROM events where id= $1",id),&event);err!=nil{
 			return nil,err
 		}
 	}
 	return &event, nil
 }
 
-func (er *eventRepository) Fetch(ctx context.Context, amount, page int) ([]entities.Event, error) {
-	events := []entities.Event{}
-	query := "SELECT * FROM events OFFSET $1 LIMIT $2"
-	rows, err := er.DB.Query(ctx, query, page*amount-amount, amount)
+func (er *eventRepository) Fetch(ctx context.Context, states []string, callerId string, w query.Window) (*query.Page[entities.Event], error) {
+	b := query.Select(eventColumns,"events",nil)
+	b.Where("state = ANY("+b.Arg(states)+")")
+	b.Where(visibleTo(b.Arg(callerId)))
+	events, err := er.query(ctx, b.Keyset(eventKey,w))
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	for rows.Next(){
-		event:=entities.Event{}
-		if err:=rows.Scan(&event.Id,&event.AuthorId,&event.Body,&event.Game,&event.Max,&event.Time,&event.NotificatedPre);err!=nil{
-			return nil,err
-		}
-		events=append(events, event)
-	}
-	return events, nil
+	page := query.Paginate(events, eventKey, w, eventPosition)
+	return &page, nil
 }
 
-func (er *eventRepository) Join(ctx context.Context,user_id,event_id string) error{
-	if _,err:=er.DB.Exec(ctx,"INSERT INTO users_events (user_id,event_id) values($1,$2)",user_id,event_id);err!=nil{
+func (er *eventRepository) Join(ctx context.Context,user_id,event_id,role string) error{
+	if _,err:=er.DB.Exec(ctx,"INSERT INTO users_events (user_id,event_id,role) values($1,$2,$3)",user_id,event_id,role);err!=nil{
 		return err
 	}
 	return nil
@@ -186,53 +248,275 @@ func (er *eventRepository) FetchMembers(ctx context.Context,id string) ([]string
 	return members,nil
 }
 
-func (er *eventRepository) Filter(ctx context.Context, game,max,time string, amount, page int) ([]entities.Event, error){
-	var q string
-	fields:=map[string]string{
-		"game":game,
-		"max":max,
-		"time":time,
+// FetchJoined returns every event the user is a member of, including archived
+// ones, for the calendar feed.
+func (er *eventRepository) FetchJoined(ctx context.Context, user_id string) ([]entities.Event, error){
+	events := []entities.Event{}
+	query := "SELECT "+qualifiedEventColumns("e")+" FROM events e JOIN users_events ue ON ue.event_id = e.id WHERE ue.user_id = $1 ORDER BY e.time"
+	rows, err := er.DB.Query(ctx, query, user_id)
+	if err != nil {
+		return nil, err
 	}
-	for f,v:=range fields{
-		if v!=""{
-			if len(q)!=0{
-				q+=fmt.Sprintf(" AND %s='%s'",f,v)
-			}else{
-				q+=fmt.Sprintf(" WHERE %s='%s'",f,v)
-			}
+	defer rows.Close()
+	for rows.Next(){
+		event:=entities.Event{}
+		if err:=scanEvent(rows,&event);err!=nil{
+			return nil,err
 		}
+		events=append(events, event)
+	}
+	return events, nil
+}
+
+func (er *eventRepository) Filter(ctx context.Context, filter EventFilter, callerId string, amount, page int) ([]entities.Event, error){
+	b:=query.Select(eventColumns,"events",eventFilterColumns).In("state",entities.ActiveEventStates)
+	b.Where(visibleTo(b.Arg(callerId)))
+	if len(filter.Games) > 0{
+		b.In("game",filter.Games)
+	}
+	if filter.Max > 0{
+		b.Eq("max",filter.Max)
+	}
+	if filter.MinMax > 0{
+		b.Gte("max",filter.MinMax)
+	}
+	if !filter.Time.IsZero(){
+		b.Eq("time",filter.Time)
+	}
+	switch {
+	case !filter.From.IsZero() && !filter.To.IsZero():
+		b.Between("time",filter.From,filter.To)
+	case !filter.From.IsZero():
+		b.Gte("time",filter.From)
+	case !filter.To.IsZero():
+		b.Lte("time",filter.To)
+	}
+	if filter.Role != ""{
+		b.Where(fmt.Sprintf(`EXISTS (SELECT 1 FROM jsonb_to_recordset(events.roles) AS r(name TEXT, slots INT)
+			WHERE r.name = %s AND r.slots > (SELECT count(*) FROM users_events ue WHERE ue.event_id = events.id AND ue.role = r.name))`,b.Arg(filter.Role)))
+	}
+	return er.query(ctx,b.OrderBy(query.Order{Field: "time"}).Page(amount,page))
+}
+
+func (er *eventRepository) Sort(ctx context.Context, orders []query.Order, callerId string, amount, page int) ([]entities.Event, error){
+	b:=query.Select(eventColumns,"events",eventSortColumns)
+	b.Where("state = ANY("+b.Arg(entities.ActiveEventStates)+")")
+	b.Where(visibleTo(b.Arg(callerId)))
+	return er.query(ctx,b.OrderBy(orders...).Page(amount,page))
+}
+
+func (er *eventRepository) query(ctx context.Context, b *query.Builder) ([]entities.Event, error){
+	sql,args,err:=b.Build()
+	if err!=nil{
+		return nil,err
 	}
 	events:=[]entities.Event{}
-	query:=fmt.Sprintf("SELECT * FROM events %s OFFSET $2 LIMIT $3",q)
-	rows,err:=er.DB.Query(ctx,query,amount*page-amount,amount)
+	rows,err:=er.DB.Query(ctx,sql,args...)
 	if err!=nil{
 		return nil,err
 	}
 	defer rows.Close()
 	for rows.Next(){
 		event:=entities.Event{}
-		if err:=rows.Scan(&event.Id,&event.AuthorId,&event.Body,&event.Game,&event.Max,&event.Time,&event.NotificatedPre);err!=nil{
+		if err:=scanEvent(rows,&event);err!=nil{
 			return nil,err
 		}
 		events=append(events,event)
 	}
 	return events,nil
 }
+// LockById reads the event bypassing the cache and locks its row until the
+// surrounding transaction ends, so membership changes are serialized.
+func (er *eventRepository) LockById(ctx context.Context, id string) (*entities.Event, error){
+	event:=entities.Event{}
+	if err:=scanEvent(er.DB.QueryRow(ctx,"SELECT "+eventColumns+" FROM events WHERE id = $1 FOR UPDATE",id),&event);err!=nil{
+		return nil,err
+	}
+	return &event,nil
+}
 
-func (er *eventRepository) Sort(ctx context.Context, field,dir string, amount, page int) ([]entities.Event, error){
-	events:=[]entities.Event{}
-	query:=fmt.Sprintf("SELECT * FROM events ORDER BY %s %s OFFSET $1 LIMIT $2",field,dir)
-	rows,err:=er.DB.Query(ctx, query,amount*page-amount,amount)
+func (er *eventRepository) CountMembers(ctx context.Context, id string) (int, error){
+	var count int
+	if err:=er.DB.QueryRow(ctx,"SELECT count(*) FROM users_events WHERE event_id = $1",id).Scan(&count);err!=nil{
+		return 0,err
+	}
+	return count,nil
+}
+
+func (er *eventRepository) IsMember(ctx context.Context, user_id, event_id string) (bool, error){
+	var exists bool
+	if err:=er.DB.QueryRow(ctx,"SELECT EXISTS (SELECT 1 FROM users_events WHERE user_id = $1 AND event_id = $2)",user_id,event_id).Scan(&exists);err!=nil{
+		return false,err
+	}
+	return exists,nil
+}
+
+func (er *eventRepository) AddToWaitlist(ctx context.Context, user_id, event_id, role string) error{
+	if _,err:=er.DB.Exec(ctx,"INSERT INTO events_waitlist (user_id,event_id,role) values($1,$2,$3) ON CONFLICT DO NOTHING",user_id,event_id,role);err!=nil{
+		return err
+	}
+	return nil
+}
+
+func (er *eventRepository) RemoveFromWaitlist(ctx context.Context, user_id, event_id string) (bool, error){
+	tag,err:=er.DB.Exec(ctx,"DELETE FROM events_waitlist WHERE user_id = $1 AND event_id = $2",user_id,event_id)
+	if err!=nil{
+		return false,err
+	}
+	return tag.RowsAffected() > 0,nil
+}
+
+// WaitlistPosition returns the 1-based position of the user in the waitlist
+// or 0 when the user is not waiting.
+func (er *eventRepository) WaitlistPosition(ctx context.Context, user_id, event_id string) (int, error){
+	var position int
+	query:="SELECT position FROM (SELECT user_id, row_number() OVER (ORDER BY seq) AS position FROM events_waitlist WHERE event_id = $1) w WHERE user_id = $2"
+	if err:=er.DB.QueryRow(ctx,query,event_id,user_id).Scan(&position);err!=nil{
+		if errors.Is(err,pgx.ErrNoRows){
+			return 0,nil
+		}
+		return 0,err
+	}
+	return position,nil
+}
+
+// PopWaitlist removes the first user waiting for one of the given roles and
+// returns its id and role, or an empty id when nobody is waiting.
+func (er *eventRepository) PopWaitlist(ctx context.Context, event_id string, roles []string) (string, string, error){
+	var id,role string
+	query:="DELETE FROM events_waitlist WHERE event_id = $1 AND user_id = (SELECT user_id FROM events_waitlist WHERE event_id = $1 AND role = ANY($2) ORDER BY seq LIMIT 1) RETURNING user_id,role"
+	if err:=er.DB.QueryRow(ctx,query,event_id,roles).Scan(&id,&role);err!=nil{
+		if errors.Is(err,pgx.ErrNoRows){
+			return "","",nil
+		}
+		return "","",err
+	}
+	return id,role,nil
+}
+
+func (er *eventRepository) FetchEnded(ctx context.Context, time time.Time) ([]entities.Event, error){
+	events := []entities.Event{}
+	rows,err:=er.DB.Query(ctx,"SELECT "+eventColumns+" FROM events WHERE end_time <= $1 AND state = $2",time,entities.EventInProgress)
 	if err!=nil{
 		return nil,err
 	}
 	defer rows.Close()
 	for rows.Next(){
 		event:=entities.Event{}
-		if err:=rows.Scan(&event.Id,&event.AuthorId,&event.Body,&event.Game,&event.Max,&event.Time,&event.NotificatedPre);err!=nil{
+		if err:=scanEvent(rows,&event);err!=nil{
 			return nil,err
 		}
-		events=append(events,event)
+		events=append(events, event)
 	}
 	return events,nil
-}
\ No newline at end of file
+}
+
+// UpdateState moves the event to a new state only if it is still in the
+// expected one, so concurrent transitions cannot overwrite each other.
+func (er *eventRepository) UpdateState(ctx context.Context, id, from, to string) (bool, error){
+	tag,err:=er.DB.Exec(ctx,"UPDATE events SET state = $1, sequence = sequence + 1 WHERE id = $2 AND state = $3",to,id,from)
+	if err!=nil{
+		return false,err
+	}
+	if er.Redis != nil {
+		if err:=er.Redis.Del(ctx,id).Err();err!=nil{
+			return false,err
+		}
+	}
+	return tag.RowsAffected() > 0,nil
+}
+
+// CountConflicts returns how many members of the event already take part in
+// another active event overlapping the given period.
+func (er *eventRepository) CountConflicts(ctx context.Context, id string, start, end time.Time) (int, error){
+	var count int
+	query:=`SELECT count(DISTINCT ue.user_id) FROM users_events ue
+		JOIN users_events other ON other.user_id = ue.user_id AND other.event_id <> ue.event_id
+		JOIN events e ON e.id = other.event_id
+		WHERE ue.event_id = $1 AND e.state = ANY($2) AND e.time < $4 AND e.end_time > $3`
+	if err:=er.DB.QueryRow(ctx,query,id,entities.ActiveEventStates,start,end).Scan(&count);err!=nil{
+		return 0,err
+	}
+	return count,nil
+}
+
+func (er *eventRepository) FindByInviteCode(ctx context.Context, code string) (*entities.Event, error){
+	event:=entities.Event{}
+	if err:=scanEvent(er.DB.QueryRow(ctx,"SELECT "+eventColumns+" FROM events WHERE invite_code = $1 AND invite_code <> ''",code),&event);err!=nil{
+		return nil,err
+	}
+	return &event,nil
+}
+
+// FetchMemberRoles maps every member of the event to the role they hold.
+func (er *eventRepository) FetchMemberRoles(ctx context.Context,id string) (map[string]string,error){
+	members:=map[string]string{}
+	rows,err:=er.DB.Query(ctx,"SELECT user_id,role FROM users_events WHERE event_id = $1",id)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		var user,role string
+		if err:=rows.Scan(&user,&role);err!=nil{
+			return nil,err
+		}
+		members[user]=role
+	}
+	return members,nil
+}
+
+func (er *eventRepository) CountRoleMembers(ctx context.Context, id string) (map[string]int, error){
+	counts:=map[string]int{}
+	rows,err:=er.DB.Query(ctx,"SELECT role,count(*) FROM users_events WHERE event_id = $1 GROUP BY role",id)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		var role string
+		var count int
+		if err:=rows.Scan(&role,&count);err!=nil{
+			return nil,err
+		}
+		counts[role]=count
+	}
+	return counts,nil
+}
+
+// CheckIn stores the first check-in of the member, it reports false when the
+// member has already checked in.
+func (er *eventRepository) CheckIn(ctx context.Context, user_id, event_id string, at time.Time) (bool, error){
+	tag,err:=er.DB.Exec(ctx,"UPDATE users_events SET checked_in_at = $3 WHERE user_id = $1 AND event_id = $2 AND checked_in_at IS NULL",user_id,event_id,at)
+	if err!=nil{
+		return false,err
+	}
+	return tag.RowsAffected() > 0,nil
+}
+
+// CloseAttendance flags members who did not check in to events started before
+// the given moment as no-shows. It returns the members of the closed events.
+func (er *eventRepository) CloseAttendance(ctx context.Context, before time.Time) ([]string, error){
+	members:=[]string{}
+	query:=`WITH closed AS (
+			UPDATE events SET attendance_checked = true
+			WHERE NOT attendance_checked AND state = ANY($1) AND time <= $2 RETURNING id
+		), flagged AS (
+			UPDATE users_events ue SET no_show = true FROM closed
+			WHERE ue.event_id = closed.id AND ue.checked_in_at IS NULL RETURNING ue.user_id
+		)
+		SELECT DISTINCT ue.user_id FROM users_events ue JOIN closed ON closed.id = ue.event_id`
+	rows,err:=er.DB.Query(ctx,query,[]string{entities.EventInProgress,entities.EventFinished},before)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		var id string
+		if err:=rows.Scan(&id);err!=nil{
+			return nil,err
+		}
+		members=append(members, id)
+	}
+	return members,nil
+}
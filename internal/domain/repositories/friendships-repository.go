@@ -2,25 +2,31 @@ package repositories
 
 import (
 	"context"
-	"github.com/jackc/pgx/v5"
+	"crap/internal/infrastructure/db/query"
 	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type FriendshipsRepository interface {
 	Add(ctx context.Context,id1,id2 string) error
 	Cancel(ctx context.Context, id1, id2 string) error
 	Accept(ctx context.Context, id1, id2 string) error
-	Fetch(ctx context.Context, id string, amount, page int) ([]string,error)
+	Fetch(ctx context.Context, id string, w query.Window) (*query.Page[string],error)
 	FetchRequests(ctx context.Context, id string, amount, page int) ([]string,error)
+	AreFriends(ctx context.Context, id1, id2 string) (bool,error)
 }
 
+var friendKey = query.Key{Column: "u.id", Type: "uuid", Id: "u.id", IdType: "uuid"}
+
 type friendshipsRepository struct{
-	DB *pgx.Conn
+	DB Querier
 }
 
-func NewFriendshipsRepository(db *pgx.Conn) FriendshipsRepository{
+func NewFriendshipsRepository(db *pgxpool.Pool) FriendshipsRepository{
 	return &friendshipsRepository{
-		DB: db,
+		DB: NewQuerier(db),
 	}
 }
 
@@ -67,9 +73,17 @@ func(fr *friendshipsRepository)	Accept(ctx context.Context, id1, id2 string) err
 	return nil
 }
 
-func(fr *friendshipsRepository)	Fetch(ctx context.Context, id string, amount, page int) ([]string,error){
+func(fr *friendshipsRepository)	Fetch(ctx context.Context, id string, w query.Window) (*query.Page[string],error){
 	friends:=[]string{}
-	rows,err:=fr.DB.Query(ctx,"SELECT * FROM users u JOIN friendships f ON f.user_id2=u.id WHERE f.user_id1=$1 AND f.relation = 'accepted' UNION SELECT u.id FROM users u JOIN friendships f ON f.user_id1=u.id WHERE f.user_id2=$1 AND f.relation = 'accepted' OFFSET $2 LIMIT $3",id,page*amount-amount,amount)
+	b:=query.Select("u.id","users u",nil)
+	user:=b.Arg(id)
+	b.Where(fmt.Sprintf(`EXISTS (SELECT 1 FROM friendships f WHERE f.relation = 'accepted'
+		AND ((f.user_id1 = %[1]s AND f.user_id2 = u.id) OR (f.user_id2 = %[1]s AND f.user_id1 = u.id)))`,user))
+	sql,args,err:=b.Keyset(friendKey,w).Build()
+	if err!=nil{
+		return nil,err
+	}
+	rows,err:=fr.DB.Query(ctx,sql,args...)
 	if err!=nil{
 		return nil,err
 	}
@@ -81,7 +95,10 @@ func(fr *friendshipsRepository)	Fetch(ctx context.Context, id string, amount, pa
 		}
 		friends=append(friends, id)
 	}
-	return friends,nil
+	page:=query.Paginate(friends,friendKey,w,func(id string) query.Cursor {
+		return query.Cursor{Value: id, Id: id}
+	})
+	return &page,nil
 }
 
 func(fr *friendshipsRepository)	FetchRequests(ctx context.Context, id string, amount, page int) ([]string,error){
@@ -100,3 +117,11 @@ func(fr *friendshipsRepository)	FetchRequests(ctx context.Context, id string, am
 	}
 	return requests,nil
 }
+
+func(fr *friendshipsRepository)	AreFriends(ctx context.Context, id1, id2 string) (bool,error){
+	var exists bool
+	if err:=fr.DB.QueryRow(ctx,"SELECT EXISTS (SELECT 1 FROM friendships WHERE ((user_id1 = $1 and user_id2 = $2) OR (user_id1 = $2 and user_id2 = $1)) AND relation = 'accepted')",id1,id2).Scan(&exists);err!=nil{
+		return false,err
+	}
+	return exists,nil
+}
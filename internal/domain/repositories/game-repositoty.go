@@ -3,27 +3,37 @@ package repositories
 import (
 	"context"
 	"crap/internal/domain/entities"
-	"fmt"
+	"crap/internal/infrastructure/db/query"
+	"strconv"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type GameRepository interface {
 	Save(ctx context.Context, game entities.Game) error
 	FindByName(ctx context.Context, name string) (*entities.Game, error)
 	FindById(ctx context.Context, id string) (*entities.Game, error)
-	Fetch(ctx context.Context, amount, page int) ([]entities.Game, error)
-	Filter(ctx context.Context, name string, amount, page int) ([]entities.Game, error)
-	Sort(ctx context.Context, field,dir string, amount, page int) ([]entities.Game, error)
+	Fetch(ctx context.Context, w query.Window) (*query.Page[entities.Game], error)
+	Filter(ctx context.Context, names []string, amount, page int) ([]entities.Game, error)
+	Sort(ctx context.Context, orders []query.Order, amount, page int) ([]entities.Game, error)
 }
 
+const gameColumns = "id,name,description,banner,picture,number_of_players,number_of_events,rating"
+
+var gameFilterColumns = query.Columns{"name": "name"}
+
+// gameKey lists games from the most played.
+var gameKey = query.Key{Column: "coalesce(number_of_players,0)", Type: "int", Id: "id", IdType: "text", Desc: true}
+
+var gameSortColumns = query.Columns{"number_of_events": "number_of_events", "number_of_players": "number_of_players", "rating": "rating"}
+
 type gameRepository struct {
-	DB *pgx.Conn
+	DB Querier
 }
 
-func NewGameRepository(db *pgx.Conn) GameRepository {
+func NewGameRepository(db *pgxpool.Pool) GameRepository {
 	return &gameRepository{
-		DB: db,
+		DB: NewQuerier(db),
 	}
 }
 
@@ -36,7 +46,7 @@ func (gr *gameRepository) Save(ctx context.Context, game entities.Game) error{
 
 func (gr *gameRepository) FindById(ctx context.Context, id string) (*entities.Game, error){
 	game:=entities.Game{}
-	if err:=gr.DB.QueryRow(ctx,"SELECT * FROM games WHERE id = $1",id).Scan(&game.Id,&game.Name,&game.Description,&game.Banner,&game.Picture,&game.NumberOfPlayers,&game.NumberOfEvents,&game.Rating);err!=nil{
+	if err:=gr.DB.QueryRow(ctx,"SELECT "+gameColumns+" FROM games WHERE id = $1",id).Scan(&game.Id,&game.Name,&game.Description,&game.Banner,&game.Picture,&game.NumberOfPlayers,&game.NumberOfEvents,&game.Rating);err!=nil{
 		return nil,err
 	}
 	return &game, nil
@@ -44,51 +54,42 @@ func (gr *gameRepository) FindById(ctx context.Context, id string) (*entities.Ga
 
 func (gr *gameRepository) FindByName(ctx context.Context, name string) (*entities.Game, error){
 	game:=entities.Game{}
-	if err:=gr.DB.QueryRow(ctx,"SELECT * FROM games WHERE name = $1",name).Scan(&game.Id,&game.Name,&game.Description,&game.Banner,&game.Picture,&game.NumberOfPlayers,&game.NumberOfEvents,&game.Rating);err!=nil{
+	if err:=gr.DB.QueryRow(ctx,"SELECT "+gameColumns+" FROM games WHERE name = $1",name).Scan(&game.Id,&game.Name,&game.Description,&game.Banner,&game.Picture,&game.NumberOfPlayers,&game.NumberOfEvents,&game.Rating);err!=nil{
 		return nil,err
 	}
 	return &game, nil
 }
 
-func (gr *gameRepository) Fetch(ctx context.Context, amount, page int) ([]entities.Game, error){
-	games := []entities.Game{}
-	rows,err:=gr.DB.Query(ctx,"SELECT * FROM games ORDER BY number_of_players DESC OFFSET $1 LIMIT $2",amount*page-amount,amount)
+func (gr *gameRepository) Fetch(ctx context.Context, w query.Window) (*query.Page[entities.Game], error){
+	games,err:=gr.query(ctx,query.Select(gameColumns,"games",nil).Keyset(gameKey,w))
 	if err!=nil{
 		return nil,err
 	}
-	defer rows.Close()
-	for rows.Next(){
-		game:=entities.Game{}
-		if err:=rows.Scan(&game.Id,&game.Name,&game.Description,&game.Banner,&game.Picture,&game.NumberOfPlayers,&game.NumberOfEvents,&game.Rating);err!=nil{
-			return nil,err
-		}
-		games=append(games, game)
+	page:=query.Paginate(games,gameKey,w,func(g entities.Game) query.Cursor {
+		return query.Cursor{Value: strconv.Itoa(g.NumberOfPlayers), Id: g.Id}
+	})
+	return &page, nil
+}
+
+func (gr *gameRepository) Filter(ctx context.Context, names []string, amount, page int) ([]entities.Game, error){
+	b:=query.Select(gameColumns,"games",gameFilterColumns)
+	if len(names) > 0{
+		b.In("name",names)
 	}
-	return games, nil
+	return gr.query(ctx,b.OrderBy(query.Order{Field: "name"}).Page(amount,page))
 }
 
-func (gr *gameRepository) Filter(ctx context.Context, name string, amount, page int) ([]entities.Game, error){
-	games:=[]entities.Game{}
-	query:="SELECT * FROM games WHERE name = $1 OFFSET $2 LIMIT $3"
-	rows,err:=gr.DB.Query(ctx, query, name, amount*page-amount,amount)
+func (gr *gameRepository) Sort(ctx context.Context, orders []query.Order, amount, page int) ([]entities.Game, error){
+	return gr.query(ctx,query.Select(gameColumns,"games",gameSortColumns).OrderBy(orders...).Page(amount,page))
+}
+
+func (gr *gameRepository) query(ctx context.Context, b *query.Builder) ([]entities.Game, error){
+	sql,args,err:=b.Build()
 	if err!=nil{
 		return nil,err
 	}
-	defer rows.Close()
-	for rows.Next(){
-		game:=entities.Game{}
-		if err:=rows.Scan(&game.Id,&game.Name,&game.Description,&game.Banner,&game.Picture,&game.NumberOfPlayers,&game.NumberOfEvents,&game.Rating);err!=nil{
-			return nil,err
-		}
-		games=append(games, game)
-	}
-	return games,nil
-}
-
-func (gr *gameRepository) Sort(ctx context.Context, field, dir string, amount, page int) ([]entities.Game, error){
 	games:=[]entities.Game{}
-	query:=fmt.Sprintf("SELECT * FROM games ORDER BY %s %s OFFSET $1 LIMIT $2",field,dir)
-	rows,err:=gr.DB.Query(ctx, query,amount*page-amount,amount)
+	rows,err:=gr.DB.Query(ctx,sql,args...)
 	if err!=nil{
 		return nil,err
 	}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"encoding/json"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const lfgQueueKey = "lfg:queue"
+
+type LfgRepository interface {
+	Enqueue(ctx context.Context, ticket entities.LfgTicket) error
+	Dequeue(ctx context.Context, user_id string) (bool, error)
+	FindByUser(ctx context.Context, user_id string) (*entities.LfgTicket, error)
+	Fetch(ctx context.Context) ([]entities.LfgTicket, error)
+	Claim(ctx context.Context, tickets []entities.LfgTicket) (bool, error)
+}
+
+// NewLfgRepository keeps the queue in Redis so it is shared between
+// instances, or in memory when Redis is not available.
+func NewLfgRepository(redis *redis.Client) LfgRepository {
+	if redis == nil {
+		return &memoryLfgRepository{
+			tickets: map[string]entities.LfgTicket{},
+		}
+	}
+	return &redisLfgRepository{
+		Redis: redis,
+	}
+}
+
+type redisLfgRepository struct {
+	Redis *redis.Client
+}
+
+func (lr *redisLfgRepository) Enqueue(ctx context.Context, ticket entities.LfgTicket) error{
+	data,err:=json.Marshal(ticket)
+	if err!=nil{
+		return err
+	}
+	return lr.Redis.HSet(ctx,lfgQueueKey,ticket.UserId.String(),data).Err()
+}
+
+func (lr *redisLfgRepository) Dequeue(ctx context.Context, user_id string) (bool, error){
+	removed,err:=lr.Redis.HDel(ctx,lfgQueueKey,user_id).Result()
+	if err!=nil{
+		return false,err
+	}
+	return removed > 0,nil
+}
+
+func (lr *redisLfgRepository) FindByUser(ctx context.Context, user_id string) (*entities.LfgTicket, error){
+	data,err:=lr.Redis.HGet(ctx,lfgQueueKey,user_id).Result()
+	if err!=nil{
+		if err == redis.Nil{
+			return nil,nil
+		}
+		return nil,err
+	}
+	ticket:=entities.LfgTicket{}
+	if err:=json.Unmarshal([]byte(data),&ticket);err!=nil{
+		return nil,err
+	}
+	return &ticket,nil
+}
+
+func (lr *redisLfgRepository) Fetch(ctx context.Context) ([]entities.LfgTicket, error){
+	tickets:=[]entities.LfgTicket{}
+	queue,err:=lr.Redis.HGetAll(ctx,lfgQueueKey).Result()
+	if err!=nil{
+		return nil,err
+	}
+	for _,data:=range queue{
+		ticket:=entities.LfgTicket{}
+		if err:=json.Unmarshal([]byte(data),&ticket);err!=nil{
+			return nil,err
+		}
+		tickets=append(tickets, ticket)
+	}
+	return tickets,nil
+}
+
+// Claim removes the tickets from the queue. If some of them were already
+// taken by another matcher the rest are put back and false is returned.
+func (lr *redisLfgRepository) Claim(ctx context.Context, tickets []entities.LfgTicket) (bool, error){
+	cmds,err:=lr.Redis.TxPipelined(ctx,func(pipe redis.Pipeliner) error {
+		for _,ticket:=range tickets{
+			pipe.HDel(ctx,lfgQueueKey,ticket.UserId.String())
+		}
+		return nil
+	})
+	if err!=nil{
+		return false,err
+	}
+	claimed:=[]entities.LfgTicket{}
+	for i,cmd:=range cmds{
+		if cmd.(*redis.IntCmd).Val() > 0{
+			claimed=append(claimed, tickets[i])
+		}
+	}
+	if len(claimed) == len(tickets){
+		return true,nil
+	}
+	for _,ticket:=range claimed{
+		if err:=lr.Enqueue(ctx,ticket);err!=nil{
+			return false,err
+		}
+	}
+	return false,nil
+}
+
+type memoryLfgRepository struct {
+	mu      sync.Mutex
+	tickets map[string]entities.LfgTicket
+}
+
+func (lr *memoryLfgRepository) Enqueue(ctx context.Context, ticket entities.LfgTicket) error{
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	lr.tickets[ticket.UserId.String()]=ticket
+	return nil
+}
+
+func (lr *memoryLfgRepository) Dequeue(ctx context.Context, user_id string) (bool, error){
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	_,ok:=lr.tickets[user_id]
+	delete(lr.tickets,user_id)
+	return ok,nil
+}
+
+func (lr *memoryLfgRepository) FindByUser(ctx context.Context, user_id string) (*entities.LfgTicket, error){
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	ticket,ok:=lr.tickets[user_id]
+	if !ok{
+		return nil,nil
+	}
+	return &ticket,nil
+}
+
+func (lr *memoryLfgRepository) Fetch(ctx context.Context) ([]entities.LfgTicket, error){
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	tickets:=make([]entities.LfgTicket,0,len(lr.tickets))
+	for _,ticket:=range lr.tickets{
+		tickets=append(tickets, ticket)
+	}
+	return tickets,nil
+}
+
+func (lr *memoryLfgRepository) Claim(ctx context.Context, tickets []entities.LfgTicket) (bool, error){
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	for _,ticket:=range tickets{
+		if _,ok:=lr.tickets[ticket.UserId.String()];!ok{
+			return false,nil
+		}
+	}
+	for _,ticket:=range tickets{
+		delete(lr.tickets,ticket.UserId.String())
+	}
+	return true,nil
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const telegramLinkPrefix = "telegram:link:"
+
+// LinkRepository keeps the one-time codes that bind a Telegram chat to an
+// account.
+type LinkRepository interface {
+	Issue(ctx context.Context, code, user_id string, ttl time.Duration) error
+	Redeem(ctx context.Context, code string) (string, error)
+}
+
+// NewLinkRepository keeps the codes in Redis, so the bot can redeem a code
+// issued by any instance, or in memory when Redis is not available.
+func NewLinkRepository(redis *redis.Client) LinkRepository {
+	if redis == nil {
+		return &memoryLinkRepository{
+			codes: map[string]memoryLink{},
+		}
+	}
+	return &redisLinkRepository{
+		Redis: redis,
+	}
+}
+
+type redisLinkRepository struct {
+	Redis *redis.Client
+}
+
+func (lr *redisLinkRepository) Issue(ctx context.Context, code, user_id string, ttl time.Duration) error{
+	return lr.Redis.Set(ctx,telegramLinkPrefix+code,user_id,ttl).Err()
+}
+
+// Redeem returns the user of the code and forgets it, so a code works once.
+// It returns an empty id for an unknown or expired code.
+func (lr *redisLinkRepository) Redeem(ctx context.Context, code string) (string, error){
+	id,err:=lr.Redis.GetDel(ctx,telegramLinkPrefix+code).Result()
+	if err!=nil{
+		if err == redis.Nil{
+			return "",nil
+		}
+		return "",err
+	}
+	return id,nil
+}
+
+type memoryLink struct {
+	userId  string
+	expires time.Time
+}
+
+type memoryLinkRepository struct {
+	mu    sync.Mutex
+	codes map[string]memoryLink
+}
+
+func (lr *memoryLinkRepository) Issue(ctx context.Context, code, user_id string, ttl time.Duration) error{
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	now:=time.Now()
+	for c,link:=range lr.codes{
+		if now.After(link.expires){
+			delete(lr.codes,c)
+		}
+	}
+	lr.codes[code]=memoryLink{userId: user_id, expires: now.Add(ttl)}
+	return nil
+}
+
+func (lr *memoryLinkRepository) Redeem(ctx context.Context, code string) (string, error){
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	link,ok:=lr.codes[code]
+	if !ok{
+		return "",nil
+	}
+	delete(lr.codes,code)
+	if time.Now().After(link.expires){
+		return "",nil
+	}
+	return link.userId,nil
+}
@@ -2,8 +2,11 @@ package repositories
 
 import (
 	"context"
-	"github.com/jackc/pgx/v5"
 	"crap/internal/domain/entities"
+	"crap/internal/infrastructure/db/query"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 
@@ -11,16 +14,20 @@ type NewsRepository interface {
 	Create(ctx context.Context, news entities.News) error
 	Save(ctx context.Context, news entities.News) error
 	FindById(ctx context.Context, id string) (*entities.News, error)
-	Fetch(ctx context.Context, amount,page int) ([]entities.News, error)
+	Fetch(ctx context.Context, w query.Window) (*query.Page[entities.News], error)
 }
 
+const newsColumns = "id,title,body,time,link,picture"
+
+var newsKey = query.Key{Column: "time", Type: "timestamptz", Id: "id", IdType: "uuid"}
+
 type newsRepository struct {
-	DB *pgx.Conn
+	DB Querier
 }
 
-func NewNewsRepository(db *pgx.Conn) NewsRepository {
+func NewNewsRepository(db *pgxpool.Pool) NewsRepository {
 	return &newsRepository{
-		DB: db,
+		DB: NewQuerier(db),
 	}
 }
 
@@ -40,15 +47,19 @@ func (nr *newsRepository) Save(ctx context.Context, news entities.News) error {
 
 func (nr *newsRepository) FindById(ctx context.Context, id string) (*entities.News, error) {
 	news := entities.News{}
-	if err:=nr.DB.QueryRow(ctx,"SELECT * FROM news WHERE id = $1",id).Scan(&news.Id,&news.Title,&news.Body,&news.Time,&news.Link,&news.Picture);err!=nil{
+	if err:=nr.DB.QueryRow(ctx,"SELECT "+newsColumns+" FROM news WHERE id = $1",id).Scan(&news.Id,&news.Title,&news.Body,&news.Time,&news.Link,&news.Picture);err!=nil{
 		return nil,err
 	}
 	return &news, nil
 }
 
-func (nr *newsRepository) Fetch(ctx context.Context, amount, page int) ([]entities.News, error) {
+func (nr *newsRepository) Fetch(ctx context.Context, w query.Window) (*query.Page[entities.News], error) {
 	somenews := []entities.News{}
-	rows,err:=nr.DB.Query(ctx,"SELECT * FROM news OREDER BY time OFFSET $1 LIMIT $2",page*amount-amount,amount)
+	sql,args,err:=query.Select(newsColumns,"news",nil).Keyset(newsKey,w).Build()
+	if err!=nil{
+		return nil,err
+	}
+	rows,err:=nr.DB.Query(ctx,sql,args...)
 	if err!=nil{
 		return nil,err
 	}
@@ -60,5 +71,8 @@ func (nr *newsRepository) Fetch(ctx context.Context, amount, page int) ([]entiti
 		}
 		somenews = append(somenews, news)
 	}
-	return somenews, nil
-}
\ No newline at end of file
+	page:=query.Paginate(somenews,newsKey,w,func(n entities.News) query.Cursor {
+		return query.Cursor{Value: n.Time.Format(time.RFC3339Nano), Id: n.Id.String()}
+	})
+	return &page, nil
+}
@@ -3,9 +3,11 @@ package repositories
 import (
 	"context"
 	"crap/internal/domain/entities"
-	"fmt"
+	"crap/internal/infrastructure/db/query"
+	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 )
 
 type NotificationRepository interface {
@@ -14,22 +16,45 @@ type NotificationRepository interface {
 	Delete(ctx context.Context, id string, nid string) error
 	DeleteAll(ctx context.Context, id string) error
 	FindById(ctx context.Context, id string) (*entities.Notification, error)
-	Fetch(ctx context.Context,id string, amount, page int) ([]entities.Notification, error)
+	Fetch(ctx context.Context,id string, filter NotificationFilter, w query.Window) (*query.Page[entities.Notification], error)
+	FetchAfter(ctx context.Context, id, nid string, limit int) ([]entities.Notification, error)
+	MarkRead(ctx context.Context, id string, nids []string, at time.Time) (int64, error)
+	MarkReadBefore(ctx context.Context, id string, before, at time.Time) (int64, error)
+	CountUnread(ctx context.Context, id string) (int, error)
+	ForgetUnread(ctx context.Context, ids ...string) error
 }
 
+// NotificationFilter holds the optional conditions of Fetch, zero values are
+// skipped.
+type NotificationFilter struct {
+	// Read is "read" or "unread".
+	Read    string
+	EventId string
+}
+
+const unreadKeyPrefix = "unread:"
+
+// unreadTTL bounds how long a cached counter can lag behind when it was not
+// forgotten, e.g. a notification removed by a cascade.
+const unreadTTL = 10*time.Minute
+
+// notificationKey lists notifications from the newest.
+var notificationKey = query.Key{Column: "n.time", Type: "timestamptz", Id: "n.id", IdType: "uuid", Desc: true}
+
 type notificationRepository struct {
-	DB  *pgx.Conn
+	DB    Querier
+	Redis *redis.Client
 }
 
-func NewNoticeRepository(db *pgx.Conn) NotificationRepository {
+func NewNoticeRepository(db *pgxpool.Pool, redis *redis.Client) NotificationRepository {
 	return &notificationRepository{
-		DB: db,
+		DB:    NewQuerier(db),
+		Redis: redis,
 	}
 }
 
 func (nr *notificationRepository) Create(ctx context.Context, notification entities.Notification) error {
-	fmt.Println(notification)
-	if _,err:=nr.DB.Exec(ctx,"INSERT INTO notifications (id,event_id,body,time) values($1,$2,$3,$4)",notification.Id,notification.EventId,notification.Body,notification.Time);err!=nil{
+	if _,err:=nr.DB.Exec(ctx,"INSERT INTO notifications (id,event_id,body,time,message) values($1,$2,$3,$4,$5) ON CONFLICT (id) DO NOTHING",notification.Id,notification.EventId,notification.Body,notification.Time,notification.Message);err!=nil{
 		return err
 	}
 	
@@ -37,7 +62,7 @@ func (nr *notificationRepository) Create(ctx context.Context, notification entit
 }
 
 func (nr *notificationRepository) CreateForUsers(ctx context.Context, notification entities.Notification, id string ) error{
-	if _,err:=nr.DB.Exec(ctx,"INSERT INTO users_notifications (user_id,notification_id) values($1,$2)",id,notification.Id);err!=nil{
+	if _,err:=nr.DB.Exec(ctx,"INSERT INTO users_notifications (user_id,notification_id) values($1,$2) ON CONFLICT DO NOTHING",id,notification.Id);err!=nil{
 		return err
 	}
 	return nil
@@ -51,7 +76,7 @@ func (nr *notificationRepository) Delete(ctx context.Context, id, nid string) er
 }
 
 func (nr *notificationRepository) DeleteAll(ctx context.Context, id string) error{
-	if _,err:=nr.DB.Exec(ctx,"DELETE FROM users_notifications WHERE user_id = $1)",id);err!=nil{
+	if _,err:=nr.DB.Exec(ctx,"DELETE FROM users_notifications WHERE user_id = $1",id);err!=nil{
 		return err
 	}
 	return nil
@@ -59,29 +84,129 @@ func (nr *notificationRepository) DeleteAll(ctx context.Context, id string) erro
 
 func (nr *notificationRepository) FindById(ctx context.Context, id string) (*entities.Notification, error) {
 	notification := entities.Notification{}
-	if err:=nr.DB.QueryRow(ctx,"SELECT * FROM notifications WHERE id = $1",id).Scan(&notification.Id,&notification.EventId,&notification.Body,&notification.Time);err!=nil{
+	if err:=nr.DB.QueryRow(ctx,"SELECT id,event_id,body,time,message FROM notifications WHERE id = $1",id).Scan(&notification.Id,&notification.EventId,&notification.Body,&notification.Time,&notification.Message);err!=nil{
 		return nil,err
 	}
 	return &notification, nil
 }
 
-func (nr *notificationRepository) Fetch(ctx context.Context,id string, amount, page int) ([]entities.Notification, error){
+func (nr *notificationRepository) Fetch(ctx context.Context,id string, filter NotificationFilter, w query.Window) (*query.Page[entities.Notification], error){
+	notifications:=[]entities.Notification{}
+	b:=query.Select("n.id,n.event_id,n.body,n.time,un.read_at,n.message","notifications n JOIN users_notifications un ON un.notification_id = n.id",nil)
+	b.Where("un.user_id = "+b.Arg(id))
+	switch filter.Read{
+	case "read":
+		b.Where("un.read_at IS NOT NULL")
+	case "unread":
+		b.Where("un.read_at IS NULL")
+	}
+	if filter.EventId != ""{
+		b.Where("n.event_id = "+b.Arg(filter.EventId))
+	}
+	sql,args,err:=b.Keyset(notificationKey,w).Build()
+	if err!=nil{
+		return nil,err
+	}
+	rows,err:=nr.DB.Query(ctx,sql,args...)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		n:=entities.Notification{}
+		if err:=rows.Scan(&n.Id,&n.EventId,&n.Body,&n.Time,&n.ReadAt,&n.Message);err!=nil{
+			return nil,err
+		}
+		notifications=append(notifications,n)
+	}
+	if err:=rows.Err();err!=nil{
+		return nil,err
+	}
+	page:=query.Paginate(notifications,notificationKey,w,func(n entities.Notification) query.Cursor {
+		return query.Cursor{Value: n.Time.Format(time.RFC3339Nano), Id: n.Id.String()}
+	})
+	return &page,nil
+}
+
+// FetchAfter returns up to limit notifications of the user that are newer
+// than the notification nid, oldest first. It is empty when nid is unknown.
+func (nr *notificationRepository) FetchAfter(ctx context.Context, id, nid string, limit int) ([]entities.Notification, error){
 	notifications:=[]entities.Notification{}
-	query:="SELECT n.id,n.event_id,n.body,n.time FROM notifications n JOIN users_notifications un ON un.notification_id = n.id WHERE un.user_id = $1 ORDER BY n.time DESC OFFSET $2 LIMIT $3"
-	rows,err:=nr.DB.Query(ctx,query,id,page*amount-amount,amount)
+	query:=`SELECT n.id,n.event_id,n.body,n.time,un.read_at,n.message FROM notifications n JOIN users_notifications un ON un.notification_id = n.id
+		WHERE un.user_id = $1 AND (n.time,n.id) > (SELECT time,id FROM notifications WHERE id = $2)
+		ORDER BY n.time,n.id LIMIT $3`
+	rows,err:=nr.DB.Query(ctx,query,id,nid,limit)
 	if err!=nil{
 		return nil,err
 	}
 	defer rows.Close()
 	for rows.Next(){
 		n:=entities.Notification{}
-		if err:=rows.Scan(&n.Id,&n.EventId,&n.Body,&n.Time);err!=nil{
+		if err:=rows.Scan(&n.Id,&n.EventId,&n.Body,&n.Time,&n.ReadAt,&n.Message);err!=nil{
 			return nil,err
 		}
 		notifications=append(notifications,n)
 	}
-	if rows.Err()!=nil{
+	if err:=rows.Err();err!=nil{
 		return nil,err
 	}
 	return notifications,nil
-}
\ No newline at end of file
+}
+
+// MarkRead marks the notifications nids of the user read and returns how
+// many were unread.
+func (nr *notificationRepository) MarkRead(ctx context.Context, id string, nids []string, at time.Time) (int64, error){
+	tag,err:=nr.DB.Exec(ctx,"UPDATE users_notifications SET read_at = $3 WHERE user_id = $1 AND notification_id = ANY($2::uuid[]) AND read_at IS NULL",id,nids,at)
+	if err!=nil{
+		return 0,err
+	}
+	return tag.RowsAffected(),nil
+}
+
+// MarkReadBefore marks every notification of the user up to before read.
+func (nr *notificationRepository) MarkReadBefore(ctx context.Context, id string, before, at time.Time) (int64, error){
+	tag,err:=nr.DB.Exec(ctx,`UPDATE users_notifications un SET read_at = $3 FROM notifications n
+		WHERE n.id = un.notification_id AND un.user_id = $1 AND n.time <= $2 AND un.read_at IS NULL`,id,before,at)
+	if err!=nil{
+		return 0,err
+	}
+	return tag.RowsAffected(),nil
+}
+
+// CountUnread returns the number of unread notifications of the user. The
+// counter is cached until ForgetUnread drops it.
+func (nr *notificationRepository) CountUnread(ctx context.Context, id string) (int, error){
+	if nr.Redis != nil{
+		count,err:=nr.Redis.Get(ctx,unreadKeyPrefix+id).Int()
+		if err == nil{
+			return count,nil
+		}
+		if err != redis.Nil{
+			return 0,err
+		}
+	}
+	count:=0
+	if err:=nr.DB.QueryRow(ctx,"SELECT count(*) FROM users_notifications WHERE user_id = $1 AND read_at IS NULL",id).Scan(&count);err!=nil{
+		return 0,err
+	}
+	if nr.Redis != nil{
+		if err:=nr.Redis.Set(ctx,unreadKeyPrefix+id,count,unreadTTL).Err();err!=nil{
+			return 0,err
+		}
+	}
+	return count,nil
+}
+
+// ForgetUnread drops the cached counters of the users. It is called once the
+// change is committed, a transaction still running could otherwise let a
+// reader cache the old count again.
+func (nr *notificationRepository) ForgetUnread(ctx context.Context, ids ...string) error{
+	if nr.Redis == nil || len(ids) == 0{
+		return nil
+	}
+	keys:=make([]string,0,len(ids))
+	for _,id:=range ids{
+		keys=append(keys,unreadKeyPrefix+id)
+	}
+	return nr.Redis.Del(ctx,keys...).Err()
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"crap/internal/infrastructure/db/query"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, messages ...entities.OutboxMessage) error
+	ClaimDue(ctx context.Context, now, lease time.Time, limit int) ([]entities.OutboxMessage, error)
+	MarkDelivered(ctx context.Context, id string, at time.Time) error
+	MarkFailed(ctx context.Context, id, reason string, next time.Time) error
+	MarkDead(ctx context.Context, id, reason string) error
+	Postpone(ctx context.Context, id string, until time.Time) error
+	FetchDead(ctx context.Context, w query.Window) (*query.Page[entities.OutboxMessage], error)
+	Replay(ctx context.Context, id string) (bool, error)
+}
+
+const outboxColumns = "id,idempotency_key,channel,payload,status,attempts,next_attempt_at,last_error,created_at,delivered_at"
+
+// outboxKey lists dead letters from the newest.
+var outboxKey = query.Key{Column: "created_at", Type: "timestamptz", Id: "id", IdType: "uuid", Desc: true}
+
+type outboxRepository struct {
+	DB Querier
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) OutboxRepository {
+	return &outboxRepository{
+		DB: NewQuerier(db),
+	}
+}
+
+// Enqueue stores the messages, skipping those whose key is already known.
+// It is meant to run in the transaction of the change being announced.
+func (or *outboxRepository) Enqueue(ctx context.Context, messages ...entities.OutboxMessage) error{
+	for _,m:=range messages{
+		if _,err:=or.DB.Exec(ctx,"INSERT INTO outbox (id,idempotency_key,channel,payload) VALUES ($1,$2,$3,$4) ON CONFLICT (idempotency_key) DO NOTHING",
+		m.Id,m.Key,m.Channel,m.Payload);err!=nil{
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimDue takes up to limit pending messages and hides them until lease, so
+// concurrent dispatchers skip them and a crashed one gives them back.
+func (or *outboxRepository) ClaimDue(ctx context.Context, now, lease time.Time, limit int) ([]entities.OutboxMessage, error){
+	query:=`UPDATE outbox SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (SELECT id FROM outbox WHERE status = $3 AND next_attempt_at <= $1 ORDER BY next_attempt_at LIMIT $4 FOR UPDATE SKIP LOCKED)
+		RETURNING `+outboxColumns
+	rows,err:=or.DB.Query(ctx,query,now,lease,entities.OutboxPending,limit)
+	if err!=nil{
+		return nil,err
+	}
+	return scanOutbox(rows)
+}
+
+func (or *outboxRepository) MarkDelivered(ctx context.Context, id string, at time.Time) error{
+	if _,err:=or.DB.Exec(ctx,"UPDATE outbox SET status = $1, delivered_at = $2, last_error = '' WHERE id = $3",entities.OutboxDelivered,at,id);err!=nil{
+		return err
+	}
+	return nil
+}
+
+func (or *outboxRepository) MarkFailed(ctx context.Context, id, reason string, next time.Time) error{
+	if _,err:=or.DB.Exec(ctx,"UPDATE outbox SET last_error = $1, next_attempt_at = $2 WHERE id = $3",reason,next,id);err!=nil{
+		return err
+	}
+	return nil
+}
+
+// Postpone hides the message until the time and gives back the attempt its
+// claim took, a postponed delivery is not a failure.
+func (or *outboxRepository) Postpone(ctx context.Context, id string, until time.Time) error{
+	if _,err:=or.DB.Exec(ctx,"UPDATE outbox SET attempts = GREATEST(attempts - 1, 0), next_attempt_at = $1 WHERE id = $2",until,id);err!=nil{
+		return err
+	}
+	return nil
+}
+
+func (or *outboxRepository) MarkDead(ctx context.Context, id, reason string) error{
+	if _,err:=or.DB.Exec(ctx,"UPDATE outbox SET status = $1, last_error = $2 WHERE id = $3",entities.OutboxDead,reason,id);err!=nil{
+		return err
+	}
+	return nil
+}
+
+func (or *outboxRepository) FetchDead(ctx context.Context, w query.Window) (*query.Page[entities.OutboxMessage], error){
+	b:=query.Select(outboxColumns,"outbox",nil)
+	b.Where("status = "+b.Arg(entities.OutboxDead))
+	sql,args,err:=b.Keyset(outboxKey,w).Build()
+	if err!=nil{
+		return nil,err
+	}
+	rows,err:=or.DB.Query(ctx,sql,args...)
+	if err!=nil{
+		return nil,err
+	}
+	messages,err:=scanOutbox(rows)
+	if err!=nil{
+		return nil,err
+	}
+	page:=query.Paginate(messages,outboxKey,w,func(m entities.OutboxMessage) query.Cursor {
+		return query.Cursor{Value: m.CreatedAt.Format(time.RFC3339Nano), Id: m.Id.String()}
+	})
+	return &page,nil
+}
+
+// Replay puts a dead letter back in the queue with a fresh attempt budget. It
+// reports false when there is no such dead letter.
+func (or *outboxRepository) Replay(ctx context.Context, id string) (bool, error){
+	tag,err:=or.DB.Exec(ctx,"UPDATE outbox SET status = $1, attempts = 0, next_attempt_at = now() WHERE id = $2 AND status = $3",entities.OutboxPending,id,entities.OutboxDead)
+	if err!=nil{
+		return false,err
+	}
+	return tag.RowsAffected() > 0,nil
+}
+
+func scanOutbox(rows pgx.Rows) ([]entities.OutboxMessage, error){
+	defer rows.Close()
+	messages:=[]entities.OutboxMessage{}
+	for rows.Next(){
+		m:=entities.OutboxMessage{}
+		if err:=rows.Scan(&m.Id,&m.Key,&m.Channel,&m.Payload,&m.Status,&m.Attempts,&m.NextAttemptAt,&m.LastError,&m.CreatedAt,&m.DeliveredAt);err!=nil{
+			return nil,err
+		}
+		messages=append(messages,m)
+	}
+	if err:=rows.Err();err!=nil{
+		return nil,err
+	}
+	return messages,nil
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type RatingRepository interface {
+	Create(ctx context.Context, rating entities.Rating) error
+	FetchGiven(ctx context.Context, id string, amount, page int) ([]entities.Rating, error)
+	FetchReceived(ctx context.Context, id string, amount, page int) ([]entities.Rating, error)
+}
+
+type ratingRepository struct {
+	DB Querier
+}
+
+func NewRatingRepository(db *pgxpool.Pool) RatingRepository {
+	return &ratingRepository{
+		DB: NewQuerier(db),
+	}
+}
+
+const ratingColumns = "id,rater_id,ratee_id,event_id,stars,comment,time"
+
+func (rr *ratingRepository) Create(ctx context.Context, rating entities.Rating) error{
+	if _,err:=rr.DB.Exec(ctx,"INSERT INTO ratings ("+ratingColumns+") values($1,$2,$3,$4,$5,$6,$7)",rating.Id,rating.RaterId,rating.RateeId,rating.EventId,rating.Stars,rating.Comment,rating.Time);err!=nil{
+		var pgErr *pgconn.PgError
+		if errors.As(err,&pgErr) && pgErr.Code == "23505"{
+			return errors.New("user is already rated for this event")
+		}
+		return err
+	}
+	return nil
+}
+
+func (rr *ratingRepository) FetchGiven(ctx context.Context, id string, amount, page int) ([]entities.Rating, error){
+	return rr.fetch(ctx,"rater_id",id,amount,page)
+}
+
+func (rr *ratingRepository) FetchReceived(ctx context.Context, id string, amount, page int) ([]entities.Rating, error){
+	return rr.fetch(ctx,"ratee_id",id,amount,page)
+}
+
+func (rr *ratingRepository) fetch(ctx context.Context, column, id string, amount, page int) ([]entities.Rating, error){
+	ratings:=[]entities.Rating{}
+	rows,err:=rr.DB.Query(ctx,"SELECT "+ratingColumns+" FROM ratings WHERE "+column+" = $1 ORDER BY time DESC OFFSET $2 LIMIT $3",id,page*amount-amount,amount)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		rating:=entities.Rating{}
+		if err:=rows.Scan(&rating.Id,&rating.RaterId,&rating.RateeId,&rating.EventId,&rating.Stars,&rating.Comment,&rating.Time);err!=nil{
+			return nil,err
+		}
+		ratings=append(ratings, rating)
+	}
+	return ratings,nil
+}
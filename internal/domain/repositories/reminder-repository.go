@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReminderRepository interface {
+	FetchOffsets(ctx context.Context, user_id string) ([]int, error)
+	SaveOffsets(ctx context.Context, user_id string, offsets []int) error
+	FetchDue(ctx context.Context, now time.Time, fallback int) ([]entities.Reminder, error)
+	MarkSent(ctx context.Context, reminder entities.Reminder) (bool, error)
+	ResetSent(ctx context.Context, event_id string) error
+}
+
+type reminderRepository struct {
+	DB Querier
+}
+
+func NewReminderRepository(db *pgxpool.Pool) ReminderRepository {
+	return &reminderRepository{
+		DB: NewQuerier(db),
+	}
+}
+
+func (rr *reminderRepository) FetchOffsets(ctx context.Context, user_id string) ([]int, error){
+	offsets:=[]int{}
+	rows,err:=rr.DB.Query(ctx,"SELECT offset_minutes FROM reminder_preferences WHERE user_id = $1 ORDER BY offset_minutes DESC",user_id)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		var offset int
+		if err:=rows.Scan(&offset);err!=nil{
+			return nil,err
+		}
+		offsets=append(offsets, offset)
+	}
+	return offsets,nil
+}
+
+func (rr *reminderRepository) SaveOffsets(ctx context.Context, user_id string, offsets []int) error{
+	if _,err:=rr.DB.Exec(ctx,"DELETE FROM reminder_preferences WHERE user_id = $1",user_id);err!=nil{
+		return err
+	}
+	if _,err:=rr.DB.Exec(ctx,"INSERT INTO reminder_preferences (user_id,offset_minutes) SELECT $1,unnest($2::int[]) ON CONFLICT DO NOTHING",user_id,offsets);err!=nil{
+		return err
+	}
+	return nil
+}
+
+// FetchDue returns, per member and event, the lead times that have already
+// passed and are missing from the ledger. Users without preferences get the
+// fallback offset. Ticks the scheduler misses are caught up on the next one.
+func (rr *reminderRepository) FetchDue(ctx context.Context, now time.Time, fallback int) ([]entities.Reminder, error){
+	reminders:=[]entities.Reminder{}
+	query:=`SELECT ue.user_id, array_agg(o.offset_minutes ORDER BY o.offset_minutes), `+qualifiedEventColumns("e")+`
+		FROM events e
+		JOIN users_events ue ON ue.event_id = e.id
+		CROSS JOIN LATERAL (
+			SELECT rp.offset_minutes FROM reminder_preferences rp WHERE rp.user_id = ue.user_id
+			UNION
+			SELECT $3::int WHERE NOT EXISTS (SELECT 1 FROM reminder_preferences rp WHERE rp.user_id = ue.user_id)
+		) o
+		WHERE e.state = ANY($1) AND e.time > $2 AND e.time - make_interval(mins => o.offset_minutes) <= $2
+		AND NOT EXISTS (SELECT 1 FROM sent_reminders sr WHERE sr.user_id = ue.user_id AND sr.event_id = e.id AND sr.offset_minutes = o.offset_minutes)
+		GROUP BY ue.user_id, e.id`
+	rows,err:=rr.DB.Query(ctx,query,[]string{entities.EventScheduled,entities.EventStarting},now,fallback)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		reminder:=entities.Reminder{}
+		dest:=append([]any{&reminder.UserId,&reminder.Offsets},eventFields(&reminder.Event)...)
+		if err:=rows.Scan(dest...);err!=nil{
+			return nil,err
+		}
+		reminders=append(reminders, reminder)
+	}
+	return reminders,nil
+}
+
+// MarkSent records the due offsets in the ledger. It reports false when
+// another run has already claimed them, so the reminder must not be sent.
+func (rr *reminderRepository) MarkSent(ctx context.Context, reminder entities.Reminder) (bool, error){
+	tag,err:=rr.DB.Exec(ctx,"INSERT INTO sent_reminders (user_id,event_id,offset_minutes) SELECT $1,$2,unnest($3::int[]) ON CONFLICT DO NOTHING",reminder.UserId,reminder.Event.Id,reminder.Offsets)
+	if err!=nil{
+		return false,err
+	}
+	return tag.RowsAffected() > 0,nil
+}
+
+func (rr *reminderRepository) ResetSent(ctx context.Context, event_id string) error{
+	if _,err:=rr.DB.Exec(ctx,"DELETE FROM sent_reminders WHERE event_id = $1",event_id);err!=nil{
+		return err
+	}
+	return nil
+}
+
+func qualifiedEventColumns(alias string) string {
+	return alias+"."+strings.ReplaceAll(eventColumns,",",","+alias+".")
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SearchRepository interface {
+	Search(ctx context.Context, text, kind, callerId string, amount, page int) ([]entities.SearchHit, error)
+}
+
+type searchRepository struct {
+	DB Querier
+}
+
+func NewSearchRepository(db *pgxpool.Pool) SearchRepository {
+	return &searchRepository{
+		DB: NewQuerier(db),
+	}
+}
+
+// prefixQuery turns free text into a tsquery where every word matches as a
+// prefix, so a half-typed word already finds results.
+func prefixQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// Search ranks events, games, news and users matching the text. Events and
+// texts are matched in both Russian and English, logins are matched as is.
+// An empty kind searches everything.
+func (sr *searchRepository) Search(ctx context.Context, text, kind, callerId string, amount, page int) ([]entities.SearchHit, error){
+	hits := []entities.SearchHit{}
+	query := prefixQuery(text)
+	if query == ""{
+		return hits,nil
+	}
+	sql := `WITH q AS (SELECT to_tsquery('russian',$1) || to_tsquery('english',$1) AS text, to_tsquery('simple',$1) AS login)
+		SELECT type,id,title,snippet,rank FROM (
+			SELECT 'event' AS type, id::text AS id, game AS title, left(coalesce(body,''),150) AS snippet, ts_rank(search,q.text)::float8 AS rank
+				FROM events, q WHERE $3 IN ('','event') AND search @@ q.text AND state = ANY($4) AND `+visibleTo("$2")+`
+			UNION ALL
+			SELECT 'game', id, name, left(description,150), ts_rank(search,q.text)::float8
+				FROM games, q WHERE $3 IN ('','game') AND search @@ q.text
+			UNION ALL
+			SELECT 'news', id::text, title, left(body,150), ts_rank(search,q.text)::float8
+				FROM news, q WHERE $3 IN ('','news') AND search @@ q.text
+			UNION ALL
+			SELECT 'user', id::text, login, '', ts_rank(search,q.login)::float8
+				FROM users, q WHERE $3 IN ('','user') AND search @@ q.login
+		) hits ORDER BY rank DESC, title OFFSET $5 LIMIT $6`
+	rows,err:=sr.DB.Query(ctx,sql,query,callerId,kind,entities.ActiveEventStates,amount*page-amount,amount)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		hit:=entities.SearchHit{}
+		if err:=rows.Scan(&hit.Type,&hit.Id,&hit.Title,&hit.Snippet,&hit.Rank);err!=nil{
+			return nil,err
+		}
+		hits=append(hits, hit)
+	}
+	if err:=rows.Err();err!=nil{
+		return nil,err
+	}
+	return hits,nil
+}
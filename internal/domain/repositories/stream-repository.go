@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const notificationStreamPrefix = "notifications:"
+
+// streamBuffer is how many notifications wait for a slow subscriber before
+// new ones are dropped. A dropped one is still in the database and comes
+// back when the client resumes.
+const streamBuffer = 32
+
+type StreamRepository interface {
+	Publish(ctx context.Context, user_id string, notification entities.Notification) error
+	// Subscribe streams the notifications published for the user. A message
+	// that cannot be read is skipped and handed to failed, which may be nil.
+	Subscribe(ctx context.Context, user_id string, failed func(error)) (<-chan entities.Notification, error)
+}
+
+// NewStreamRepository fans notifications out through Redis pub/sub so a
+// client gets them from whichever instance it is connected to, or in memory
+// when Redis is not available.
+func NewStreamRepository(redis *redis.Client) StreamRepository {
+	if redis == nil {
+		return &memoryStreamRepository{
+			subscribers: map[string]map[chan entities.Notification]struct{}{},
+		}
+	}
+	return &redisStreamRepository{
+		Redis: redis,
+	}
+}
+
+type redisStreamRepository struct {
+	Redis *redis.Client
+}
+
+func (sr *redisStreamRepository) Publish(ctx context.Context, user_id string, notification entities.Notification) error{
+	data,err:=json.Marshal(notification)
+	if err!=nil{
+		return err
+	}
+	return sr.Redis.Publish(ctx,notificationStreamPrefix+user_id,data).Err()
+}
+
+// Subscribe returns the notifications published for the user from now on.
+// The channel is closed and the subscription dropped when ctx is done.
+func (sr *redisStreamRepository) Subscribe(ctx context.Context, user_id string, failed func(error)) (<-chan entities.Notification, error){
+	pubsub:=sr.Redis.Subscribe(ctx,notificationStreamPrefix+user_id)
+	if _,err:=pubsub.Receive(ctx);err!=nil{
+		pubsub.Close()
+		return nil,err
+	}
+	out:=make(chan entities.Notification,streamBuffer)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+		messages:=pubsub.Channel()
+		for{
+			select{
+			case <-ctx.Done():
+				return
+			case msg,ok:=<-messages:
+				if !ok{
+					return
+				}
+				notification:=entities.Notification{}
+				if err:=json.Unmarshal([]byte(msg.Payload),&notification);err!=nil{
+					if failed != nil{
+						failed(fmt.Errorf("cannot decode streamed notification: %w",err))
+					}
+					continue
+				}
+				select{
+				case out<-notification:
+				default:
+				}
+			}
+		}
+	}()
+	return out,nil
+}
+
+type memoryStreamRepository struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan entities.Notification]struct{}
+}
+
+func (sr *memoryStreamRepository) Publish(ctx context.Context, user_id string, notification entities.Notification) error{
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	for ch:=range sr.subscribers[user_id]{
+		select{
+		case ch<-notification:
+		default:
+		}
+	}
+	return nil
+}
+
+func (sr *memoryStreamRepository) Subscribe(ctx context.Context, user_id string, failed func(error)) (<-chan entities.Notification, error){
+	ch:=make(chan entities.Notification,streamBuffer)
+	sr.mu.Lock()
+	if sr.subscribers[user_id] == nil{
+		sr.subscribers[user_id]=map[chan entities.Notification]struct{}{}
+	}
+	sr.subscribers[user_id][ch]=struct{}{}
+	sr.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		sr.mu.Lock()
+		defer sr.mu.Unlock()
+		delete(sr.subscribers[user_id],ch)
+		if len(sr.subscribers[user_id]) == 0{
+			delete(sr.subscribers,user_id)
+		}
+		close(ch)
+	}()
+	return ch,nil
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type TemplateRepository interface {
+	Create(ctx context.Context, template entities.EventTemplate) error
+	FindById(ctx context.Context, id string) (*entities.EventTemplate, error)
+	FetchAvailable(ctx context.Context, user_id string) ([]entities.EventTemplate, error)
+	Delete(ctx context.Context, id string) error
+	Share(ctx context.Context, id, user_id string) error
+	IsSharedWith(ctx context.Context, id, user_id string) (bool, error)
+}
+
+const templateColumns = "id,author_id,name,body,game,max,duration,time_zone,roles,author_role,visibility,min_reliability"
+
+func scanTemplate(row pgx.Row, template *entities.EventTemplate) error {
+	return row.Scan(&template.Id,&template.AuthorId,&template.Name,&template.Body,&template.Game,&template.Max,&template.Duration,&template.TimeZone,&template.Roles,&template.AuthorRole,&template.Visibility,&template.MinReliability)
+}
+
+type templateRepository struct {
+	DB Querier
+}
+
+func NewTemplateRepository(db *pgxpool.Pool) TemplateRepository {
+	return &templateRepository{
+		DB: NewQuerier(db),
+	}
+}
+
+func (tr *templateRepository) Create(ctx context.Context, template entities.EventTemplate) error{
+	if _,err:=tr.DB.Exec(ctx,"INSERT INTO event_templates ("+templateColumns+") values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)",template.Id,template.AuthorId,template.Name,template.Body,template.Game,template.Max,template.Duration,template.TimeZone,template.Roles,template.AuthorRole,template.Visibility,template.MinReliability);err!=nil{
+		var pgErr *pgconn.PgError
+		if errors.As(err,&pgErr) && pgErr.Code == "23505"{
+			return errors.New("template with this name already exists")
+		}
+		return err
+	}
+	return nil
+}
+
+func (tr *templateRepository) FindById(ctx context.Context, id string) (*entities.EventTemplate, error){
+	template:=entities.EventTemplate{}
+	if err:=scanTemplate(tr.DB.QueryRow(ctx,"SELECT "+templateColumns+" FROM event_templates WHERE id = $1",id),&template);err!=nil{
+		return nil,err
+	}
+	return &template,nil
+}
+
+// FetchAvailable returns the user's own templates followed by the ones
+// friends shared with the user.
+func (tr *templateRepository) FetchAvailable(ctx context.Context, user_id string) ([]entities.EventTemplate, error){
+	templates:=[]entities.EventTemplate{}
+	query:="SELECT "+templateColumns+" FROM event_templates WHERE author_id = $1 OR id IN (SELECT template_id FROM event_templates_shares WHERE user_id = $1) ORDER BY author_id = $1 DESC, name"
+	rows,err:=tr.DB.Query(ctx,query,user_id)
+	if err!=nil{
+		return nil,err
+	}
+	defer rows.Close()
+	for rows.Next(){
+		template:=entities.EventTemplate{}
+		if err:=scanTemplate(rows,&template);err!=nil{
+			return nil,err
+		}
+		templates=append(templates, template)
+	}
+	return templates,nil
+}
+
+func (tr *templateRepository) Delete(ctx context.Context, id string) error{
+	if _,err:=tr.DB.Exec(ctx,"DELETE FROM event_templates WHERE id = $1",id);err!=nil{
+		return err
+	}
+	return nil
+}
+
+func (tr *templateRepository) Share(ctx context.Context, id, user_id string) error{
+	if _,err:=tr.DB.Exec(ctx,"INSERT INTO event_templates_shares (template_id,user_id) values($1,$2) ON CONFLICT DO NOTHING",id,user_id);err!=nil{
+		return err
+	}
+	return nil
+}
+
+func (tr *templateRepository) IsSharedWith(ctx context.Context, id, user_id string) (bool, error){
+	var exists bool
+	if err:=tr.DB.QueryRow(ctx,"SELECT EXISTS (SELECT 1 FROM event_templates_shares WHERE template_id = $1 AND user_id = $2)",id,user_id).Scan(&exists);err!=nil{
+		return false,err
+	}
+	return exists,nil
+}
@@ -2,20 +2,97 @@ package repositories
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"math/rand/v2"
 	"time"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Transactor interface {
-	WithinTransaction(context.Context, func(c context.Context) (any, error)) (any, error)
+	WithinTransaction(context.Context, func(c context.Context) (any, error), ...TxOption) (any, error)
+}
+
+// Querier is what the repositories run their statements on. It uses the
+// transaction carried by the context, so a repository called inside
+// WithinTransaction takes part in it, and the pool otherwise.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type querier struct {
+	Pool *pgxpool.Pool
+}
+
+func NewQuerier(db *pgxpool.Pool) Querier {
+	return &querier{
+		Pool: db,
+	}
+}
+
+func (q *querier) conn(ctx context.Context) Querier {
+	if tx := txFrom(ctx); tx != nil {
+		return tx
+	}
+	return q.Pool
+}
+
+func (q *querier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return q.conn(ctx).Exec(ctx, sql, args...)
+}
+
+func (q *querier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return q.conn(ctx).Query(ctx, sql, args...)
+}
+
+func (q *querier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return q.conn(ctx).QueryRow(ctx, sql, args...)
+}
+
+const txRetryDelay = 20*time.Millisecond
+
+type txConfig struct {
+	options pgx.TxOptions
+	retries int
+}
+
+// TxOption tunes a single WithinTransaction call.
+type TxOption func(*txConfig)
+
+// WithIsolation runs the transaction at the given isolation level instead of
+// the server default, read committed.
+func WithIsolation(level pgx.TxIsoLevel) TxOption {
+	return func(c *txConfig) {
+		c.options.IsoLevel = level
+	}
+}
+
+// WithReadOnly starts a read only transaction.
+func WithReadOnly() TxOption {
+	return func(c *txConfig) {
+		c.options.AccessMode = pgx.ReadOnly
+	}
+}
+
+// WithRetries runs the transaction up to n more times after a serialization
+// failure or a deadlock. Retries are off by default: only opt in when the
+// function has no side effects outside the database, e.g. no cache writes,
+// and does not change state it captured.
+func WithRetries(n int) TxOption {
+	return func(c *txConfig) {
+		c.retries = max(n, 0)
+	}
 }
 
 type transactor struct {
-	DB *pgx.Conn
+	DB *pgxpool.Pool
 }
 
-func NewTransactor(db *pgx.Conn) Transactor {
+func NewTransactor(db *pgxpool.Pool) Transactor {
 	return &transactor{
 		DB: db,
 	}
@@ -23,31 +100,72 @@ func NewTransactor(db *pgx.Conn) Transactor {
 
 type txKey struct{}
 
-func injectTx(ctx context.Context, tx *pgx.Tx) context.Context {
+func injectTx(ctx context.Context, tx pgx.Tx) context.Context {
 	return context.WithValue(ctx, txKey{}, tx)
 }
 
-func (t *transactor) WithinTransaction(ctx context.Context, tFunc func(ctx context.Context) (any, error)) (any, error) {
-	txCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
+func txFrom(ctx context.Context) pgx.Tx {
+	tx, _ := ctx.Value(txKey{}).(pgx.Tx)
+	return tx
+}
 
-	tx,err := t.DB.BeginTx(txCtx, pgx.TxOptions{})
-	if err!=nil{
-		return nil,err
+// WithinTransaction runs tFunc in a transaction and commits it when tFunc
+// succeeds. Called inside another transaction it opens a savepoint, so a
+// failing inner call rolls back only its own work. With WithRetries the
+// outermost call is run again when the database aborts it on a serialization
+// failure or a deadlock. A failed rollback is returned with the error of tFunc.
+func (t *transactor) WithinTransaction(ctx context.Context, tFunc func(ctx context.Context) (any, error), opts ...TxOption) (any, error) {
+	cfg := txConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if parent := txFrom(ctx); parent != nil {
+		return t.run(ctx, tFunc, func(c context.Context) (pgx.Tx, error) {
+			return parent.Begin(c)
+		})
+	}
+	for attempt := 0; ; attempt++ {
+		res, err := t.run(ctx, tFunc, func(c context.Context) (pgx.Tx, error) {
+			return t.DB.BeginTx(c, cfg.options)
+		})
+		if err == nil || attempt >= cfg.retries || !retryable(err) {
+			return res, err
+		}
+		delay := txRetryDelay<<attempt + rand.N(txRetryDelay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
 	}
-	ctxTime,cancel:=context.WithTimeout(context.Background(),5*time.Second)
+}
+
+func (t *transactor) run(ctx context.Context, tFunc func(ctx context.Context) (any, error), begin func(context.Context) (pgx.Tx, error)) (any, error) {
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctxTime, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	res, err := tFunc(injectTx(ctx, &tx))
+	res, err := tFunc(injectTx(ctx, tx))
 	if err != nil {
-		if err := tx.Rollback(ctxTime); err != nil {
-			log.Printf("cannot rollback transaction: %v", err)
+		if rbErr := tx.Rollback(ctxTime); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			return nil, errors.Join(err, fmt.Errorf("cannot rollback transaction: %w", rbErr))
 		}
 		return nil, err
 	}
-
 	if err := tx.Commit(ctxTime); err != nil {
-		log.Printf("cannot commit transaction: %v", err)
+		return nil, err
 	}
-
 	return res, nil
 }
+
+// retryable reports whether the database aborted the transaction only
+// because of a concurrent one, so running it again may succeed.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
@@ -3,12 +3,14 @@ package repositories
 import (
 	"context"
 	"crap/internal/domain/entities"
+	"crap/internal/infrastructure/db/query"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -17,24 +19,35 @@ type UserRepository interface{
 	FindById(ctx context.Context, id string) (*entities.User, error)
 	Save(ctx context.Context, user entities.User) error
 	ExistByLoginOrTg(ctx context.Context, login, tg string) (bool,error)
-	Fetch(ctx context.Context, amount, page int) ([]entities.User, error)
+	Fetch(ctx context.Context, w query.Window) (*query.Page[entities.User], error)
 	FindBy(ctx context.Context,vari, val string) (*entities.User, error)
+	UpdateRating(ctx context.Context, id string) error
+	UpdateReliability(ctx context.Context, ids []string) error
+	FetchCalendarToken(ctx context.Context, id string) (string, error)
+	SaveCalendarToken(ctx context.Context, id, token string) error
 }
 
+const userColumns = "id,login,telegram,chat_id,rating,total_rating,number_of_ratings,games,password,avatar,discord,date_of_register,time_zone,reliability,language"
+
+var userLookupColumns = query.Columns{"id": "id", "login": "login", "telegram": "telegram", "chat_id": "chat_id"}
+
+// userKey lists users from the best rated down.
+var userKey = query.Key{Column: "coalesce(rating,0)", Type: "numeric", Id: "id", IdType: "uuid", Desc: true}
+
 type userRepository struct {
-	DB *pgx.Conn
+	DB Querier
 	Redis *redis.Client
 }
 
-func NewUserRepository(db *pgx.Conn, redis *redis.Client) UserRepository {
+func NewUserRepository(db *pgxpool.Pool, redis *redis.Client) UserRepository {
 	return &userRepository{
-		DB: db,
+		DB: NewQuerier(db),
 		Redis: redis,
 	}
 }
 
 func (ur *userRepository) Create(ctx context.Context, user entities.User) error {
-	if _,err := ur.DB.Exec(ctx,"INSERT INTO users (id,login,telegram,password,date_of_register) VALUES ($1,$2,$3,$4,$5)", user.Id,user.Login,user.Telegram,user.Password,user.DateOfRegister);err!=nil{
+	if _,err := ur.DB.Exec(ctx,"INSERT INTO users (id,login,telegram,password,date_of_register,time_zone,language) VALUES ($1,$2,$3,$4,$5,$6,$7)", user.Id,user.Login,user.Telegram,user.Password,user.DateOfRegister,user.TimeZone,user.Language);err!=nil{
 		return err
 	}
 	if ur.Redis != nil {
@@ -48,8 +61,8 @@ func (ur *userRepository) Create(ctx context.Context, user entities.User) error
 }
 
 func (ur *userRepository) Save(ctx context.Context, user entities.User) error {
-	if _,err := ur.DB.Exec(ctx,"UPDATE users SET chat_id=$1,rating=$2,total_rating=$3,number_of_ratings=$4,games=$5,avatar=$6,discord=$7, date_of_register=$8 where id = $9",
-	user.ChatId,user.Rating,user.TotalRating,user.NumberOfRatings,user.Games,user.Avatar,user.Discord,user.DateOfRegister,user.Id);err!=nil {
+	if _,err := ur.DB.Exec(ctx,"UPDATE users SET chat_id=$1,games=$2,avatar=$3,discord=$4, date_of_register=$5, time_zone=$6, language=$7 where id = $8",
+	user.ChatId,user.Games,user.Avatar,user.Discord,user.DateOfRegister,user.TimeZone,user.Language,user.Id);err!=nil {
 		return err
 	}
 	if ur.Redis != nil {
@@ -75,10 +88,14 @@ func (ur *userRepository) ExistByLoginOrTg(ctx context.Context, login, tg string
 	return true,nil
 }
 
+// FindBy looks a user up by one of the unique columns in userLookupColumns.
 func (ur *userRepository) FindBy(ctx context.Context,vari,val string) (*entities.User, error){
 		user:=entities.User{}
-		query:=fmt.Sprintf("SELECT id,login,telegram,chat_id,rating,total_rating,number_of_ratings,games,password,avatar,discord,date_of_register from users where %s = $1",vari)
-		if err := ur.DB.QueryRow(ctx,query,val).Scan(
+		sql,args,err:=query.Select(userColumns,"users",userLookupColumns).Eq(vari,val).Build()
+		if err!=nil{
+			return nil,err
+		}
+		if err := ur.DB.QueryRow(ctx,sql,args...).Scan(
 			&user.Id,
 			&user.Login,
 			&user.Telegram,
@@ -91,6 +108,9 @@ func (ur *userRepository) FindBy(ctx context.Context,vari,val string) (*entities
 			&user.Avatar,
 			&user.Discord,
 			&user.DateOfRegister,
+			&user.TimeZone,
+			&user.Reliability,
+			&user.Language,
 		)
 		err != nil {
 			return nil,err
@@ -139,10 +159,13 @@ func (ur *userRepository) FindById(ctx context.Context, id string) (*entities.Us
 	return user, nil
 }
 
-func (ur *userRepository) Fetch(ctx context.Context, amount, page int) ([]entities.User, error){
+func (ur *userRepository) Fetch(ctx context.Context, w query.Window) (*query.Page[entities.User], error){
 	users := []entities.User{}
-	query := "SELECT * FROM users ORDER BY rating DESC OFFSET $1 LIMIT $2"
-	rows, err := ur.DB.Query(ctx, query, page*amount-amount, amount)
+	sql, args, err := query.Select(userColumns,"users",nil).Keyset(userKey,w).Build()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ur.DB.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +185,9 @@ func (ur *userRepository) Fetch(ctx context.Context, amount, page int) ([]entiti
 		&user.Avatar,
 		&user.Discord,
 		&user.DateOfRegister,
+		&user.TimeZone,
+		&user.Reliability,
+		&user.Language,
 		)
 		err != nil {
 			return nil, err
@@ -171,6 +197,68 @@ func (ur *userRepository) Fetch(ctx context.Context, amount, page int) ([]entiti
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return users, nil
+	page := query.Paginate(users, userKey, w, func(u entities.User) query.Cursor {
+		return query.Cursor{Value: strconv.FormatFloat(u.Rating, 'f', -1, 64), Id: u.Id.String()}
+	})
+	return &page, nil
+}
+
+
+// UpdateRating recomputes the user's rating from the ratings received, rounded
+// to half a star, and drops the cached copy.
+func (ur *userRepository) UpdateRating(ctx context.Context, id string) error{
+	query:=`UPDATE users SET rating = r.rating, total_rating = r.total, number_of_ratings = r.number
+		FROM (SELECT COALESCE(round(avg(stars)*2)/2,0) AS rating, COALESCE(sum(stars),0) AS total, count(*) AS number FROM ratings WHERE ratee_id = $1) r
+		WHERE id = $1`
+	if _,err:=ur.DB.Exec(ctx,query,id);err!=nil{
+		return err
+	}
+	if ur.Redis != nil {
+		if err:=ur.Redis.Del(ctx,id).Err();err!=nil{
+			return err
+		}
+	}
+	return nil
 }
 
+// UpdateReliability recomputes the share of closed events the users checked
+// in to. Users without such events keep the full score.
+func (ur *userRepository) UpdateReliability(ctx context.Context, ids []string) error{
+	query:=`UPDATE users u SET reliability = COALESCE(r.score,1)
+		FROM (SELECT ue.user_id, round(avg(CASE WHEN ue.no_show THEN 0 ELSE 1 END),2) AS score
+			FROM users_events ue JOIN events e ON e.id = ue.event_id
+			WHERE e.attendance_checked AND ue.user_id::text = ANY($1) GROUP BY ue.user_id) r
+		WHERE u.id = r.user_id`
+	if _,err:=ur.DB.Exec(ctx,query,ids);err!=nil{
+		return err
+	}
+	if ur.Redis != nil && len(ids) > 0 {
+		if err:=ur.Redis.Del(ctx,ids...).Err();err!=nil{
+			return err
+		}
+	}
+	return nil
+}
+
+// FetchCalendarToken returns the secret of the user's calendar feed, empty
+// when the feed was never requested or the user does not exist.
+func (ur *userRepository) FetchCalendarToken(ctx context.Context, id string) (string, error){
+	var token *string
+	if err:=ur.DB.QueryRow(ctx,"SELECT calendar_token FROM users WHERE id = $1",id).Scan(&token);err!=nil{
+		if errors.Is(err, pgx.ErrNoRows){
+			return "",nil
+		}
+		return "",err
+	}
+	if token == nil{
+		return "",nil
+	}
+	return *token,nil
+}
+
+func (ur *userRepository) SaveCalendarToken(ctx context.Context, id, token string) error{
+	if _,err:=ur.DB.Exec(ctx,"UPDATE users SET calendar_token = $1 WHERE id = $2",token,id);err!=nil{
+		return err
+	}
+	return nil
+}
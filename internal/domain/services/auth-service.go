@@ -6,6 +6,7 @@ import (
 	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
 	"crap/internal/dto"
+	"crap/internal/locales"
 	"errors"
 	"fmt"
 	"time"
@@ -45,12 +46,18 @@ func (as *authService) Register(ctx context.Context,req dto.RegisterRequest) (*e
 	if err != nil {
 		return nil, err
 	}
+	timeZone := req.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
 	user := entities.User{
 		Id:       uuid.New(),
 		Login:    req.Login,
 		Telegram: req.Telegram,
 		Password: hashPassword,
 		DateOfRegister: time.Date(time.Now().Year(),time.Now().Month(),time.Now().Day(),0,0,0,0,time.Now().Location()),
+		TimeZone: timeZone,
+		Language: locales.Resolve(req.Language),
 	}
 	if err := as.UserRepository.Create(ctx, user); err != nil {
 		return nil, err
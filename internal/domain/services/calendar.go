@@ -0,0 +1,43 @@
+package services
+
+import (
+	"crap/config"
+	"crap/internal/domain/entities"
+	"crap/pkg/ical"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidCalendarToken = errors.New("invalid calendar token")
+
+// renderCalendar turns events into an ICS document. Cancelled events stay in
+// the output so subscribed clients drop them on the next refresh.
+func renderCalendar(cfg *config.Config, name string, events []entities.Event) []byte {
+	calendar := ical.Calendar{
+		ProdId: fmt.Sprintf("-//%s//%s//RU", cfg.App.Name, cfg.App.Version),
+		Name:   name,
+	}
+	now := time.Now()
+	for _, event := range events {
+		status := ical.StatusConfirmed
+		if event.State == entities.EventCancelled {
+			status = ical.StatusCancelled
+		}
+		description := "Игра: " + event.Game
+		if event.Body != "" {
+			description += "\n" + event.Body
+		}
+		calendar.Events = append(calendar.Events, ical.Event{
+			Uid:         fmt.Sprintf("%s@%s", event.Id, cfg.App.Name),
+			Sequence:    event.Sequence,
+			Stamp:       now,
+			Start:       event.Time,
+			End:         event.EndTime,
+			Summary:     event.Game,
+			Description: description,
+			Status:      status,
+		})
+	}
+	return calendar.Marshal()
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"crap/config"
 	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
+	"crap/internal/locales"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,7 +15,7 @@ import (
 
 type CommentService interface {
 	AddComment(ctx context.Context, req dto.AddCommentRequest) (*entities.Comment, error)
-	GetComments(ctx context.Context, req dto.GetCommentsRequest) ([]entities.Comment, error)
+	GetComments(ctx context.Context, req dto.GetCommentsRequest) (*query.Page[entities.Comment], error)
 }
 
 type commentService struct{
@@ -20,16 +23,20 @@ type commentService struct{
 	UserRepository repositories.UserRepository
 	EventRepository repositories.EventRepository
 	NewsRepository repositories.NewsRepository
+	NotificationService NotificationService
 	Transactor        repositories.Transactor
+	Config *config.Config
 }
 
-func NewCommentService(cr repositories.CommentRepository,ur repositories.UserRepository, er repositories.EventRepository, nr repositories.NewsRepository,t repositories.Transactor) CommentService{
+func NewCommentService(cr repositories.CommentRepository,ur repositories.UserRepository, er repositories.EventRepository, nr repositories.NewsRepository, ns NotificationService,t repositories.Transactor, cfg *config.Config) CommentService{
 	return &commentService{
 		CommentRepository: cr,
 		UserRepository: ur,
 		EventRepository: er,
 		NewsRepository: nr,
+		NotificationService: ns,
 		Transactor: t,
+		Config: cfg,
 	}
 }
 
@@ -57,6 +64,12 @@ func(cs *commentService) AddComment(ctx context.Context,req dto.AddCommentReques
 			if err:=cs.CommentRepository.AddToUser(c,receiver.Id.String(),comment.Id.String());err!=nil{
 				return nil,err
 			}
+			if receiver.Id != user.Id{
+				msg:=entities.Message{Key: locales.ProfileComment, Params: entities.MessageParams{Login: user.Login}}
+				if err:=cs.NotificationService.NotifyUser(c,entities.Event{},receiver.Id.String(),entities.NotificationComment,msg);err!=nil{
+					return nil,err
+				}
+			}
 		case "events":
 			event,err:=cs.EventRepository.FindById(c,req.ReceiverId)
 			if err!=nil{
@@ -65,6 +78,13 @@ func(cs *commentService) AddComment(ctx context.Context,req dto.AddCommentReques
 			if err:=cs.CommentRepository.AddToEvent(c,event.Id.String(),comment.Id.String());err!=nil{
 				return nil,err
 			}
+			if event.AuthorId != user.Id{
+				msg:=eventMessage(cs.Config,locales.EventComment,*event)
+				msg.Params.Login=user.Login
+				if err:=cs.NotificationService.NotifyUser(c,*event,event.AuthorId.String(),entities.NotificationComment,msg);err!=nil{
+					return nil,err
+				}
+			}
 		case "news":
 			news,err:=cs.NewsRepository.FindById(c,req.ReceiverId)
 			if err!=nil{
@@ -82,28 +102,19 @@ func(cs *commentService) AddComment(ctx context.Context,req dto.AddCommentReques
 	return res.(*entities.Comment),nil
 }
 
-func(cs *commentService) GetComments(ctx context.Context, req dto.GetCommentsRequest) ([]entities.Comment, error){
-	comments:=[]entities.Comment{}
+func(cs *commentService) GetComments(ctx context.Context, req dto.GetCommentsRequest) (*query.Page[entities.Comment], error){
+	w,err:=window(req.CursorRequest)
+	if err!=nil{
+		return nil,err
+	}
 	switch req.Whose{
 	case "user":
-		var err error
-		comments,err=cs.CommentRepository.FetchFromUser(ctx,req.Id,req.Amount,req.Page)
-		if err!=nil{
-			return nil,err
-		}
+		return cs.CommentRepository.FetchFromUser(ctx,req.Id,w)
 	case "event":
-		var err error
-		comments,err=cs.CommentRepository.FetchFromEvent(ctx,req.Id,req.Amount,req.Page)
-		if err!=nil{
-			return nil,err
-		}
+		return cs.CommentRepository.FetchFromEvent(ctx,req.Id,w)
 	case "news":
-		var err error
-		comments,err=cs.CommentRepository.FetchFromNews(ctx,req.Id,req.Amount,req.Page)
-		if err!=nil{
-			return nil,err
-		}
+		return cs.CommentRepository.FetchFromNews(ctx,req.Id,w)
 	}
-	return comments,nil
+	return &query.Page[entities.Comment]{Items: []entities.Comment{}},nil
 }
 
@@ -2,50 +2,375 @@ package services
 
 import (
 	"context"
+	"crap/config"
+	"crypto/rand"
+	"encoding/hex"
 	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
+	"crap/internal/locales"
 	"errors"
+	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type EventService interface {
 	CreateEvent(ctx context.Context,req dto.CreateEventRequest) (*entities.Event, error)
-	GetById(ctx context.Context, id string) (*entities.Event, error)
-	FetchEvents(ctx context.Context, req dto.PaginationRequest) ([]entities.Event, error)
+	GetById(ctx context.Context, id, callerId string) (*entities.Event, error)
+	GetDetails(ctx context.Context, id, callerId string) (*dto.EventDetailsResponse, error)
+	GetCalendar(ctx context.Context, id, callerId string) ([]byte, error)
+	FetchEvents(ctx context.Context, req dto.GetEventsRequest, callerId string) (*query.Page[entities.Event], error)
 	FindUpcoming(ctx context.Context, time time.Time) ([]entities.Event, error)
+	FindEnded(ctx context.Context, time time.Time) ([]entities.Event, error)
+	Transition(ctx context.Context, event entities.Event, to string) error
+	StartEvent(ctx context.Context, event entities.Event) (*entities.Event, error)
 	DeleteEvent(ctx context.Context, id string) error
 	Save(ctx context.Context, event entities.Event) error
-	Join(ctx context.Context, req dto.JoinToEventRequest) error
+	Join(ctx context.Context, req dto.JoinToEventRequest) (*dto.JoinEventResponse, error)
+	JoinByInvite(ctx context.Context, code, role, callerId string) (*dto.JoinEventResponse, error)
 	Unjoin(ctx context.Context, req dto.UnjoinFromEventRequest) error
-	GetSorted(ctx context.Context, req dto.EventsSortRequest) ([]entities.Event, error)
-	GetFiltered(ctx context.Context, req dto.EventsFilterRequest) ([]entities.Event, error)
+	GetSorted(ctx context.Context, req dto.EventsSortRequest, callerId string) ([]entities.Event, error)
+	GetFiltered(ctx context.Context, req dto.EventsFilterRequest, callerId string) ([]entities.Event, error)
+	MaterializeNext(ctx context.Context, event entities.Event) (*entities.Event, error)
+	SkipOccurrence(ctx context.Context, req dto.SkipOccurrenceRequest, callerId string) error
+	EditEvent(ctx context.Context, id string, req dto.EditEventRequest, callerId string) (*entities.Event, error)
+	CancelEvent(ctx context.Context, id string, req dto.CancelEventRequest, callerId string) error
+	CheckIn(ctx context.Context, id, callerId string) error
+	CloseAttendance(ctx context.Context, now time.Time) (int, error)
 }
 
 type eventService struct {
 	EventRepository repositories.EventRepository
 	UserRepository  repositories.UserRepository
 	GameRepository  repositories.GameRepository
+	FriendshipsRepository repositories.FriendshipsRepository
+	ReminderRepository repositories.ReminderRepository
+	TemplateRepository repositories.TemplateRepository
+	NotificationService NotificationService
 	Transactor      repositories.Transactor
+	Config          *config.Config
 }
 
 func NewEventService(
 	eventRepository repositories.EventRepository,
 	userRepository repositories.UserRepository,
 	gameRepository repositories.GameRepository,
-	transactor repositories.Transactor) EventService {
+	friendshipsRepository repositories.FriendshipsRepository,
+	reminderRepository repositories.ReminderRepository,
+	templateRepository repositories.TemplateRepository,
+	notificationService NotificationService,
+	transactor repositories.Transactor,
+	cfg *config.Config) EventService {
 	return &eventService{
 		EventRepository: eventRepository,
 		UserRepository:  userRepository,
 		GameRepository:  gameRepository,
+		FriendshipsRepository: friendshipsRepository,
+		ReminderRepository: reminderRepository,
+		TemplateRepository: templateRepository,
+		NotificationService: notificationService,
 		Transactor:      transactor,
+		Config:          cfg,
 	}
 }
 
+// parseStartTime accepts an RFC 3339 timestamp or, when the offset is omitted,
+// a wall-clock time that is interpreted in the given IANA zone.
+func parseStartTime(value, zone string) (time.Time, error) {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return time.Time{}, errors.New("unknown time zone: " + zone)
+	}
+	start, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		start, err = time.ParseInLocation("2006-01-02T15:04:05", value, loc)
+		if err != nil {
+			return time.Time{}, errors.New("start time must be in RFC 3339 format")
+		}
+	}
+	return start.In(loc), nil
+}
+
+func (es *eventService) checkHorizon(start time.Time) error {
+	now := time.Now()
+	if start.Before(now.Add(es.Config.Event.MinLead)) {
+		return fmt.Errorf("event must start at least %v from now", es.Config.Event.MinLead)
+	}
+	if es.Config.Event.MaxHorizon > 0 && start.After(now.Add(es.Config.Event.MaxHorizon)) {
+		return fmt.Errorf("event cannot start more than %v from now", es.Config.Event.MaxHorizon)
+	}
+	return nil
+}
+
+// checkInWindow returns how long before and after the start of an event
+// members can confirm they showed up.
+func (es *eventService) checkInWindow() (time.Duration, time.Duration) {
+	before, after := es.Config.Event.CheckInBefore, es.Config.Event.CheckInAfter
+	if before <= 0 {
+		before = 15*time.Minute
+	}
+	if after <= 0 {
+		after = 15*time.Minute
+	}
+	return before, after
+}
+
+func (es *eventService) eventDuration(minutes int) time.Duration {
+	if minutes > 0 {
+		return time.Minute*time.Duration(minutes)
+	}
+	if es.Config.Event.Duration > 0 {
+		return es.Config.Event.Duration
+	}
+	return 2*time.Hour
+}
+
+func buildRecurrence(req *dto.RecurrenceRequest, zone string) (*entities.Recurrence, error) {
+	if req == nil {
+		return nil, nil
+	}
+	recurrence := entities.Recurrence{
+		Freq:     req.Freq,
+		Interval: max(req.Interval, 1),
+		Weekdays: req.Weekdays,
+		Count:    req.Count,
+	}
+	if req.Until != "" {
+		until, err := parseStartTime(req.Until, zone)
+		if err != nil {
+			return nil, err
+		}
+		recurrence.Until = &until
+	}
+	return &recurrence, nil
+}
+
+// buildRoles turns the requested slots into the event composition. The
+// capacity of such an event is the total number of slots.
+func buildRoles(req []dto.RoleSlotRequest, authorRole string) ([]entities.RoleSlot, int, error) {
+	if len(req) == 0 {
+		if authorRole != "" {
+			return nil, 0, errors.New("event has no role slots")
+		}
+		return nil, 0, nil
+	}
+	roles := make([]entities.RoleSlot, 0, len(req))
+	capacity := 0
+	for _, slot := range req {
+		if slices.ContainsFunc(roles, func(r entities.RoleSlot) bool { return r.Name == slot.Name }) {
+			return nil, 0, errors.New("duplicate role: " + slot.Name)
+		}
+		roles = append(roles, entities.RoleSlot{Name: slot.Name, Slots: slot.Slots})
+		capacity += slot.Slots
+	}
+	if !slices.ContainsFunc(roles, func(r entities.RoleSlot) bool { return r.Name == authorRole }) {
+		return nil, 0, errors.New("author role must be one of the event roles")
+	}
+	return roles, capacity, nil
+}
+
+// openRoles lists the roles that still have free slots. An event without
+// roles is represented by the empty role while it is not full.
+func (es *eventService) openRoles(ctx context.Context, event entities.Event) ([]string, error) {
+	open := []string{}
+	if len(event.Roles) == 0 {
+		members, err := es.EventRepository.CountMembers(ctx, event.Id.String())
+		if err != nil {
+			return nil, err
+		}
+		if members < event.Max {
+			open = append(open, "")
+		}
+		return open, nil
+	}
+	counts, err := es.EventRepository.CountRoleMembers(ctx, event.Id.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range event.Roles {
+		if counts[role.Name] < role.Slots {
+			open = append(open, role.Name)
+		}
+	}
+	return open, nil
+}
+
+func newInviteCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// canSee reports whether the caller may open the event. Invite-only events are
+// hidden from everyone who has not joined them yet.
+func (es *eventService) canSee(ctx context.Context, event entities.Event, callerId string) error {
+	if event.Visibility == entities.VisibilityPublic || event.Visibility == "" || event.AuthorId.String() == callerId {
+		return nil
+	}
+	if callerId != "" {
+		member, err := es.EventRepository.IsMember(ctx, callerId, event.Id.String())
+		if err != nil {
+			return err
+		}
+		if member {
+			return nil
+		}
+		if event.Visibility == entities.VisibilityFriends {
+			friends, err := es.FriendshipsRepository.AreFriends(ctx, event.AuthorId.String(), callerId)
+			if err != nil {
+				return err
+			}
+			if friends {
+				return nil
+			}
+		}
+	}
+	if event.Visibility == entities.VisibilityFriends {
+		return errors.New("event is available to the author's friends only")
+	}
+	return errors.New("event is available by invitation only")
+}
+
+// localize renders event times in the caller's stored zone, or in the zone
+// the event was planned in when the caller is unknown. Invite codes are only
+// shown to the author.
+func (es *eventService) localize(ctx context.Context, callerId string, events ...*entities.Event) {
+	var loc *time.Location
+	if callerId != "" {
+		if user, err := es.UserRepository.FindById(ctx, callerId); err == nil {
+			loc = user.Location()
+		}
+	}
+	for _, event := range events {
+		if event.AuthorId.String() != callerId {
+			event.InviteCode = ""
+		}
+		if loc != nil {
+			event.Time = event.Time.In(loc)
+		} else {
+			event.Time = event.Time.In(event.Location())
+		}
+	}
+}
+
+func (es *eventService) localizeAll(ctx context.Context, callerId string, events []entities.Event) {
+	ptrs := make([]*entities.Event, len(events))
+	for i := range events {
+		ptrs[i] = &events[i]
+	}
+	es.localize(ctx, callerId, ptrs...)
+}
+
+// splitList splits a comma separated query value, dropping empty items.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseFilterTime parses an optional RFC 3339 filter value.
+func parseFilterTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// applyTemplate fills the fields the request leaves empty from the template.
+// A template can be used by its author and by the friends it was shared with.
+func (es *eventService) applyTemplate(ctx context.Context, req *dto.CreateEventRequest) error {
+	template, err := es.TemplateRepository.FindById(ctx, req.TemplateId)
+	if err != nil {
+		return err
+	}
+	if template.AuthorId.String() != req.AuthorId {
+		shared, err := es.TemplateRepository.IsSharedWith(ctx, req.TemplateId, req.AuthorId)
+		if err != nil {
+			return err
+		}
+		if !shared {
+			return errors.New("template is not available")
+		}
+	}
+	if req.Game == "" {
+		req.Game = template.Game
+	}
+	if req.Body == "" {
+		req.Body = template.Body
+	}
+	if len(req.Roles) == 0 && req.Max == 0 {
+		req.Max = template.Max
+		for _, role := range template.Roles {
+			req.Roles = append(req.Roles, dto.RoleSlotRequest{Name: role.Name, Slots: role.Slots})
+		}
+		if req.AuthorRole == "" {
+			req.AuthorRole = template.AuthorRole
+		}
+	}
+	if req.Duration == 0 {
+		req.Duration = template.Duration
+	}
+	if req.TimeZone == "" {
+		req.TimeZone = template.TimeZone
+	}
+	if req.Visibility == "" {
+		req.Visibility = template.Visibility
+	}
+	if req.MinReliability == 0 {
+		req.MinReliability = template.MinReliability
+	}
+	return nil
+}
+
 func (es *eventService)	CreateEvent(ctx context.Context, req dto.CreateEventRequest) (*entities.Event, error){
+	if req.TemplateId != ""{
+		if err:=es.applyTemplate(ctx,&req);err!=nil{
+			return nil,err
+		}
+	}
+	start,err:=parseStartTime(req.StartAt,req.TimeZone)
+	if err!=nil{
+		return nil,err
+	}
+	if err:=es.checkHorizon(start);err!=nil{
+		return nil,err
+	}
+	recurrence,err:=buildRecurrence(req.Recurrence,req.TimeZone)
+	if err!=nil{
+		return nil,err
+	}
+	visibility:=req.Visibility
+	if visibility == ""{
+		visibility = entities.VisibilityPublic
+	}
+	roles,capacity,err:=buildRoles(req.Roles,req.AuthorRole)
+	if err!=nil{
+		return nil,err
+	}
+	if roles == nil{
+		if req.Max <= 0{
+			return nil,errors.New("max is required")
+		}
+		capacity = req.Max
+	}
+	var inviteCode string
+	if visibility == entities.VisibilityInvite{
+		if inviteCode,err=newInviteCode();err!=nil{
+			return nil,err
+		}
+	}
 	res,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
 		user,err:=es.UserRepository.FindById(c,req.AuthorId)
 		if err!=nil{
@@ -63,13 +388,20 @@ func (es *eventService)	CreateEvent(ctx context.Context, req dto.CreateEventRequ
 			AuthorId: user.Id,
 			Body: req.Body,
 			Game: game.Name,
-			Max: req.Max,
-			Time: time.Now().Add(time.Minute*time.Duration(req.Minute)),
-		}
-		if req.Minute <= 10 {
-			event.NotificatedPre = true
+			Max: capacity,
+			Time: start,
+			TimeZone: req.TimeZone,
+			Recurrence: recurrence,
+			Occurrence: 1,
+			State: entities.EventScheduled,
+			EndTime: start.Add(es.eventDuration(req.Duration)),
+			Visibility: visibility,
+			InviteCode: inviteCode,
+			Roles: roles,
+			MinReliability: req.MinReliability,
 		}
-		if err:=es.EventRepository.Create(c,event);err!=nil{
+		event.SeriesId = event.Id
+		if err:=es.EventRepository.Create(c,event,req.AuthorRole);err!=nil{
 			return nil,err
 		}
 		game.NumberOfEvents++
@@ -85,19 +417,78 @@ func (es *eventService)	CreateEvent(ctx context.Context, req dto.CreateEventRequ
 	return res.(*entities.Event),nil
 }
 
-func (es *eventService)	GetById(ctx context.Context, id string) (*entities.Event, error){
+func (es *eventService)	GetById(ctx context.Context, id, callerId string) (*entities.Event, error){
 	event,err:=es.EventRepository.FindById(ctx,id)
 	if err!=nil{
 		return nil,err
 	}
+	if err:=es.canSee(ctx,*event,callerId);err!=nil{
+		return nil,err
+	}
+	es.localize(ctx,callerId,event)
 	return event,nil
 }
 
-func (es *eventService)	FetchEvents(ctx context.Context, req dto.PaginationRequest) ([]entities.Event, error){
-	events,err:=es.EventRepository.Fetch(ctx,req.Amount,req.Page)
+func (es *eventService)	GetDetails(ctx context.Context, id, callerId string) (*dto.EventDetailsResponse, error){
+	event,err:=es.GetById(ctx,id,callerId)
 	if err!=nil{
 		return nil,err
 	}
+	members,err:=es.EventRepository.CountMembers(ctx,id)
+	if err!=nil{
+		return nil,err
+	}
+	details:=dto.EventDetailsResponse{
+		Event: *event,
+		Members: members,
+		FreeSlots: max(event.Max-members,0),
+	}
+	if len(event.Roles) > 0{
+		counts,err:=es.EventRepository.CountRoleMembers(ctx,id)
+		if err!=nil{
+			return nil,err
+		}
+		for _,role:=range event.Roles{
+			details.Roles = append(details.Roles, dto.RoleAvailability{
+				Name: role.Name,
+				Slots: role.Slots,
+				Free: max(role.Slots-counts[role.Name],0),
+			})
+		}
+	}
+	if callerId != ""{
+		position,err:=es.EventRepository.WaitlistPosition(ctx,callerId,id)
+		if err!=nil{
+			return nil,err
+		}
+		details.WaitlistPosition = position
+	}
+	return &details,nil
+}
+
+// GetCalendar renders a single event as an ICS file for a one-off import.
+func (es *eventService)	GetCalendar(ctx context.Context, id, callerId string) ([]byte, error){
+	event,err:=es.GetById(ctx,id,callerId)
+	if err!=nil{
+		return nil,err
+	}
+	return renderCalendar(es.Config,"",[]entities.Event{*event}),nil
+}
+
+func (es *eventService)	FetchEvents(ctx context.Context, req dto.GetEventsRequest, callerId string) (*query.Page[entities.Event], error){
+	states:=entities.ActiveEventStates
+	if req.State != ""{
+		states=[]string{req.State}
+	}
+	w,err:=window(req.CursorRequest)
+	if err!=nil{
+		return nil,err
+	}
+	events,err:=es.EventRepository.Fetch(ctx,states,callerId,w)
+	if err!=nil{
+		return nil,err
+	}
+	es.localizeAll(ctx,callerId,events.Items)
 	return events,nil
 }
 
@@ -109,6 +500,50 @@ func (es *eventService)	FindUpcoming(ctx context.Context, time time.Time) ([]ent
 	return events,nil
 }
 
+func (es *eventService)	FindEnded(ctx context.Context, time time.Time) ([]entities.Event, error){
+	events,err:=es.EventRepository.FetchEnded(ctx,time)
+	if err!=nil{
+		return nil,err
+	}
+	return events,nil
+}
+
+// Transition moves the event through its lifecycle:
+// scheduled -> starting -> in_progress -> finished, or to cancelled before it starts.
+func (es *eventService) Transition(ctx context.Context, event entities.Event, to string) error{
+	if !event.CanTransition(to){
+		return fmt.Errorf("event cannot move from %s to %s",event.State,to)
+	}
+	moved,err:=es.EventRepository.UpdateState(ctx,event.Id.String(),event.State,to)
+	if err!=nil{
+		return err
+	}
+	if !moved{
+		return errors.New("event state was changed concurrently")
+	}
+	return nil
+}
+
+// StartEvent moves the event in progress and queues the start message with the
+// check-in button in the same transaction. The next occurrence of a recurring
+// event is created there too, so a failure leaves the event to be started
+// again instead of ending the series. It returns the next occurrence, if any.
+func (es *eventService) StartEvent(ctx context.Context, event entities.Event) (*entities.Event, error){
+	res,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		if err:=es.Transition(c,event,entities.EventInProgress);err!=nil{
+			return nil,err
+		}
+		if err:=es.NotificationService.AnnounceStart(c,event);err!=nil{
+			return nil,err
+		}
+		return es.MaterializeNext(c,event)
+	})
+	if err!=nil{
+		return nil,err
+	}
+	return res.(*entities.Event),nil
+}
+
 func (es eventService) Save(c context.Context, event entities.Event) error {
 	if err := es.EventRepository.Save(c, event); err != nil {
 		return err
@@ -133,41 +568,129 @@ func (es *eventService)	DeleteEvent(ctx context.Context, id string) error{
 	return nil
 }
 
-func (es *eventService)	Join(ctx context.Context, req dto.JoinToEventRequest) error{
-	_,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
-		user,err:=es.UserRepository.FindById(c,req.UserId)
+func (es *eventService)	Join(ctx context.Context, req dto.JoinToEventRequest) (*dto.JoinEventResponse, error){
+	return es.join(ctx,req.UserId,req.EventId,req.Role,false)
+}
+
+// JoinByInvite joins the caller to the event the invite code was issued for,
+// whatever visibility the event has.
+func (es *eventService)	JoinByInvite(ctx context.Context, code, role, callerId string) (*dto.JoinEventResponse, error){
+	event,err:=es.EventRepository.FindByInviteCode(ctx,code)
+	if err!=nil{
+		if errors.Is(err,pgx.ErrNoRows){
+			return nil,errors.New("invite code is not valid")
+		}
+		return nil,err
+	}
+	return es.join(ctx,callerId,event.Id.String(),role,true)
+}
+
+// join adds the user to the event while there are free slots and puts the
+// user on the waitlist otherwise. When the event has role slots the user
+// claims one of them and only that role's capacity is checked. The event row
+// stays locked for the whole transaction so concurrent joins cannot overfill it,
+// and an event that has started, finished or was cancelled takes nobody.
+func (es *eventService)	join(ctx context.Context, userId, eventId, role string, invited bool) (*dto.JoinEventResponse, error){
+	res,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		user,err:=es.UserRepository.FindById(c,userId)
 		if err!=nil{
 			return nil,err
 		}
-		event,err:=es.EventRepository.FindById(c,req.EventId)
+		event,err:=es.EventRepository.LockById(c,eventId)
 		if err!=nil{
 			return nil,err
 		}
-		if err:=es.EventRepository.Join(c,user.Id.String(),event.Id.String());err!=nil{
+		if !event.Open(){
+			return nil,errors.New("event is no longer open for joining")
+		}
+		if !invited{
+			if err:=es.canSee(c,*event,user.Id.String());err!=nil{
+				return nil,err
+			}
+		}
+		if user.Reliability < event.MinReliability{
+			return nil,fmt.Errorf("event requires reliability of at least %.2f",event.MinReliability)
+		}
+		member,err:=es.EventRepository.IsMember(c,user.Id.String(),event.Id.String())
+		if err!=nil{
 			return nil,err
 		}
-		return nil,nil
+		if member{
+			return nil,errors.New("user already joined the event")
+		}
+		if len(event.Roles) > 0{
+			if _,ok:=event.Role(role);!ok{
+				return nil,errors.New("choose one of the event roles")
+			}
+		}else if role != ""{
+			return nil,errors.New("event has no role slots")
+		}
+		open,err:=es.openRoles(c,*event)
+		if err!=nil{
+			return nil,err
+		}
+		position,err:=es.EventRepository.WaitlistPosition(c,user.Id.String(),event.Id.String())
+		if err!=nil{
+			return nil,err
+		}
+		if slices.Contains(open,role){
+			if position > 0{
+				if _,err:=es.EventRepository.RemoveFromWaitlist(c,user.Id.String(),event.Id.String());err!=nil{
+					return nil,err
+				}
+			}
+			if err:=es.EventRepository.Join(c,user.Id.String(),event.Id.String(),role);err!=nil{
+				return nil,err
+			}
+			return &dto.JoinEventResponse{Status: "joined", Role: role},nil
+		}
+		if position == 0{
+			if err:=es.EventRepository.AddToWaitlist(c,user.Id.String(),event.Id.String(),role);err!=nil{
+				return nil,err
+			}
+			position,err=es.EventRepository.WaitlistPosition(c,user.Id.String(),event.Id.String())
+			if err!=nil{
+				return nil,err
+			}
+		}
+		return &dto.JoinEventResponse{Status: "waitlisted", Position: position, Role: role},nil
 	})
 	if err!=nil{
-		return err
+		return nil,err
 	}
-	return nil
+	return res.(*dto.JoinEventResponse),nil
 }
 
+// Unjoin removes the user from the event or from its waitlist. A freed slot
+// goes to the first waitlisted player, who is notified about it.
 func (es *eventService)	Unjoin(ctx context.Context, req dto.UnjoinFromEventRequest) error{
 	_,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
 		user,err:=es.UserRepository.FindById(c,req.UserId)
 		if err!=nil{
 			return nil,err
 		}
-		event,err:=es.EventRepository.FindById(c,req.EventId)
+		event,err:=es.EventRepository.LockById(c,req.EventId)
 		if err!=nil{
 			return nil,err
 		}
+		member,err:=es.EventRepository.IsMember(c,user.Id.String(),event.Id.String())
+		if err!=nil{
+			return nil,err
+		}
+		if !member{
+			waiting,err:=es.EventRepository.RemoveFromWaitlist(c,user.Id.String(),event.Id.String())
+			if err!=nil{
+				return nil,err
+			}
+			if !waiting{
+				return nil,errors.New("user is not a member of the event")
+			}
+			return nil,nil
+		}
 		if err:=es.EventRepository.Unjoin(c,user.Id.String(),event.Id.String());err!=nil{
 			return nil,err
 		}
-		return nil,nil
+		return nil,es.promoteWaitlisted(c,*event)
 	})
 	if err!=nil{
 		return err
@@ -175,18 +698,382 @@ func (es *eventService)	Unjoin(ctx context.Context, req dto.UnjoinFromEventReque
 	return nil
 }
 
-func (es *eventService) GetSorted(ctx context.Context, req dto.EventsSortRequest) ([]entities.Event, error){
-	events,err:=es.EventRepository.Sort(ctx,req.Field,req.Direction,req.Amount,req.Page)
+// promoteWaitlisted fills free slots of a locked event from its waitlist,
+// each waiting player only takes a slot of the role they asked for.
+func (es *eventService) promoteWaitlisted(ctx context.Context, event entities.Event) error{
+	for{
+		open,err:=es.openRoles(ctx,event)
+		if err!=nil{
+			return err
+		}
+		if len(open) == 0{
+			return nil
+		}
+		id,role,err:=es.EventRepository.PopWaitlist(ctx,event.Id.String(),open)
+		if err!=nil{
+			return err
+		}
+		if id == ""{
+			return nil
+		}
+		if err:=es.EventRepository.Join(ctx,id,event.Id.String(),role);err!=nil{
+			return err
+		}
+		msg:=eventMessage(es.Config,locales.WaitlistPromoted,event)
+		if err:=es.NotificationService.NotifyUser(ctx,event,id,entities.NotificationWaitlist,msg);err!=nil{
+			return err
+		}
+	}
+}
+
+func (es *eventService) GetSorted(ctx context.Context, req dto.EventsSortRequest, callerId string) ([]entities.Event, error){
+	events,err:=es.EventRepository.Sort(ctx,query.ParseOrder(req.Field,req.Direction),callerId,req.Amount,req.Page)
 	if err!=nil{
 		return nil,err
 	}
+	es.localizeAll(ctx,callerId,events)
 	return events,nil
 }
 
-func (es *eventService)	GetFiltered(ctx context.Context, req dto.EventsFilterRequest) ([]entities.Event, error){
-	events,err:=es.EventRepository.Filter(ctx,req.Game,req.Max,req.Time,req.Amount,req.Page)
+func (es *eventService)	GetFiltered(ctx context.Context, req dto.EventsFilterRequest, callerId string) ([]entities.Event, error){
+	filter:=repositories.EventFilter{
+		Games: splitList(req.Game),
+		Max: req.Max,
+		MinMax: req.MinMax,
+		Role: req.Role,
+	}
+	var err error
+	if filter.Time,err=parseFilterTime(req.Time);err!=nil{
+		return nil,err
+	}
+	if filter.From,err=parseFilterTime(req.From);err!=nil{
+		return nil,err
+	}
+	if filter.To,err=parseFilterTime(req.To);err!=nil{
+		return nil,err
+	}
+	events,err:=es.EventRepository.Filter(ctx,filter,callerId,req.Amount,req.Page)
 	if err!=nil{
 		return nil,err
 	}
+	es.localizeAll(ctx,callerId,events)
 	return events,nil
 }
+
+// MaterializeNext creates the occurrence that follows a recurring event and
+// carries its members over. It returns nil when the series has ended.
+func (es *eventService) MaterializeNext(ctx context.Context, event entities.Event) (*entities.Event, error){
+	if event.Recurrence == nil {
+		return nil,nil
+	}
+	next,occurrence,ok:=event.Recurrence.Next(event.Time,event.Occurrence,event.Location())
+	if !ok{
+		return nil,nil
+	}
+	res,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		members,err:=es.EventRepository.FetchMemberRoles(c,event.Id.String())
+		if err!=nil{
+			return nil,err
+		}
+		following:=event
+		following.Id = uuid.New()
+		following.Time = next
+		following.Occurrence = occurrence
+		following.State = entities.EventScheduled
+		following.EndTime = next.Add(event.EndTime.Sub(event.Time))
+		if following.InviteCode != ""{
+			if following.InviteCode,err=newInviteCode();err!=nil{
+				return nil,err
+			}
+		}
+		if err:=es.EventRepository.Create(c,following,members[following.AuthorId.String()]);err!=nil{
+			return nil,err
+		}
+		for id,role:=range members{
+			if id == following.AuthorId.String(){
+				continue
+			}
+			if err:=es.EventRepository.Join(c,id,following.Id.String(),role);err!=nil{
+				return nil,err
+			}
+		}
+		return &following,nil
+	})
+	if err!=nil{
+		return nil,err
+	}
+	return res.(*entities.Event),nil
+}
+
+// SkipOccurrence cancels a single occurrence of a recurring event. Skipping
+// the occurrence that is already materialized replaces it with the next one.
+// Once that occurrence has started the skip belongs to the next one, so the
+// event must still be open.
+func (es *eventService) SkipOccurrence(ctx context.Context, req dto.SkipOccurrenceRequest, callerId string) error{
+	_,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		event,err:=es.EventRepository.LockById(c,req.EventId)
+		if err!=nil{
+			return nil,err
+		}
+		if event.AuthorId.String() != callerId{
+			return nil,errors.New("only the author can skip an occurrence")
+		}
+		if event.Recurrence == nil{
+			return nil,errors.New("event is not recurring")
+		}
+		if !event.Open(){
+			return nil,errors.New("occurrence has already started, skip one of the next occurrences")
+		}
+		date,err:=parseStartTime(req.Date,event.TimeZone)
+		if err!=nil{
+			return nil,err
+		}
+		if date.Before(event.Time){
+			return nil,errors.New("occurrence is in the past")
+		}
+		if !date.Equal(event.Time){
+			current,occurrence:=event.Time,event.Occurrence
+			for current.Before(date){
+				var ok bool
+				current,occurrence,ok=event.Recurrence.Next(current,occurrence,event.Location())
+				if !ok{
+					break
+				}
+			}
+			if !current.Equal(date){
+				return nil,errors.New("event has no occurrence at this time")
+			}
+		}
+		event.Recurrence.Exceptions = append(event.Recurrence.Exceptions, date)
+		if !date.Equal(event.Time){
+			return nil,es.EventRepository.Save(c,*event)
+		}
+		if _,err:=es.MaterializeNext(c,*event);err!=nil{
+			return nil,err
+		}
+		return nil,es.Transition(c,*event,entities.EventCancelled)
+	})
+	if err!=nil{
+		return err
+	}
+	return nil
+}
+
+// EditEvent applies the author's changes to an event that has not started yet
+// and tells every member what has changed.
+func (es *eventService) EditEvent(ctx context.Context, id string, req dto.EditEventRequest, callerId string) (*entities.Event, error){
+	res,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		event,err:=es.EventRepository.LockById(c,id)
+		if err!=nil{
+			return nil,err
+		}
+		if event.AuthorId.String() != callerId{
+			return nil,errors.New("only the author can edit the event")
+		}
+		if event.State != entities.EventScheduled && event.State != entities.EventStarting{
+			return nil,errors.New("event can no longer be edited")
+		}
+		changes:=[]string{}
+		if req.Body != nil && *req.Body != event.Body{
+			event.Body = *req.Body
+			changes = append(changes, entities.ChangeBody)
+		}
+		if req.Game != nil{
+			game,err:=es.GameRepository.FindById(c,*req.Game)
+			if err!=nil{
+				return nil,err
+			}
+			author,err:=es.UserRepository.FindById(c,callerId)
+			if err!=nil{
+				return nil,err
+			}
+			if !slices.Contains(author.Games,game.Id){
+				return nil,errors.New("user does not have this game")
+			}
+			if game.Name != event.Game{
+				event.Game = game.Name
+				changes = append(changes, entities.ChangeGame)
+			}
+		}
+		grown:=false
+		visibilityChanged:=false
+		if req.Max != nil && *req.Max != event.Max{
+			if len(event.Roles) > 0{
+				return nil,errors.New("max of an event with roles is the sum of its role slots")
+			}
+			members,err:=es.EventRepository.CountMembers(c,event.Id.String())
+			if err!=nil{
+				return nil,err
+			}
+			if *req.Max < members{
+				return nil,fmt.Errorf("max cannot be lower than the current number of members (%d)",members)
+			}
+			grown = *req.Max > event.Max
+			event.Max = *req.Max
+			changes = append(changes, entities.ChangeMax)
+		}
+		if req.TimeZone != nil{
+			event.TimeZone = *req.TimeZone
+		}
+		if req.MinReliability != nil && *req.MinReliability != event.MinReliability{
+			event.MinReliability = *req.MinReliability
+			changes = append(changes, entities.ChangeMinReliability)
+		}
+		if req.Visibility != nil && *req.Visibility != event.Visibility{
+			event.Visibility = *req.Visibility
+			if event.Visibility == entities.VisibilityInvite && event.InviteCode == ""{
+				if event.InviteCode,err=newInviteCode();err!=nil{
+					return nil,err
+				}
+			}
+			if event.Visibility != entities.VisibilityInvite{
+				event.InviteCode = ""
+			}
+			visibilityChanged = true
+		}
+		if req.StartAt != nil || req.Duration != nil{
+			if event.State != entities.EventScheduled{
+				return nil,errors.New("time cannot be changed once the event is starting")
+			}
+			start:=event.Time
+			if req.StartAt != nil{
+				start,err=parseStartTime(*req.StartAt,event.TimeZone)
+				if err!=nil{
+					return nil,err
+				}
+				if err:=es.checkHorizon(start);err!=nil{
+					return nil,err
+				}
+			}
+			duration:=event.EndTime.Sub(event.Time)
+			if req.Duration != nil{
+				duration = es.eventDuration(*req.Duration)
+			}
+			end:=start.Add(duration)
+			conflicts,err:=es.EventRepository.CountConflicts(c,event.Id.String(),start,end)
+			if err!=nil{
+				return nil,err
+			}
+			if conflicts > 0{
+				return nil,fmt.Errorf("%d members already have another event at this time",conflicts)
+			}
+			if !start.Equal(event.Time) || !end.Equal(event.EndTime){
+				event.Time = start
+				event.EndTime = end
+				if err:=es.ReminderRepository.ResetSent(c,event.Id.String());err!=nil{
+					return nil,err
+				}
+				changes = append(changes, entities.ChangeTime)
+			}
+		}
+		if len(changes) == 0 && !visibilityChanged{
+			return nil,errors.New("nothing to change")
+		}
+		if err:=es.EventRepository.Save(c,*event);err!=nil{
+			return nil,err
+		}
+		if grown{
+			if err:=es.promoteWaitlisted(c,*event);err!=nil{
+				return nil,err
+			}
+		}
+		if len(changes) > 0{
+			msg:=eventMessage(es.Config,locales.EventChanged,*event)
+			msg.Params.Max = event.Max
+			msg.Params.MinReliability = event.MinReliability
+			msg.Params.Changes = changes
+			if err:=es.NotificationService.CreateNotification(c,*event,entities.NotificationEvent,msg);err!=nil{
+				return nil,err
+			}
+		}
+		return event,nil
+	})
+	if err!=nil{
+		return nil,err
+	}
+	return res.(*entities.Event),nil
+}
+
+// CancelEvent cancels an event that has not started yet. For a recurring event
+// no further occurrences are created.
+func (es *eventService) CancelEvent(ctx context.Context, id string, req dto.CancelEventRequest, callerId string) error{
+	_,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		event,err:=es.EventRepository.LockById(c,id)
+		if err!=nil{
+			return nil,err
+		}
+		if event.AuthorId.String() != callerId{
+			return nil,errors.New("only the author can cancel the event")
+		}
+		if err:=es.Transition(c,*event,entities.EventCancelled);err!=nil{
+			return nil,err
+		}
+		msg:=eventMessage(es.Config,locales.EventCancelled,*event)
+		msg.Params.Reason = req.Reason
+		if err:=es.NotificationService.CreateNotification(c,*event,entities.NotificationEvent,msg);err!=nil{
+			return nil,err
+		}
+		return nil,nil
+	})
+	if err!=nil{
+		return err
+	}
+	return nil
+}
+
+// CheckIn confirms that the member showed up. It is accepted only inside the
+// check-in window around the start of the event.
+func (es *eventService) CheckIn(ctx context.Context, id, callerId string) error{
+	event,err:=es.EventRepository.FindById(ctx,id)
+	if err!=nil{
+		return err
+	}
+	if event.State == entities.EventCancelled || event.State == entities.EventFinished{
+		return errors.New("event is over")
+	}
+	before,after:=es.checkInWindow()
+	opens,closes:=event.Time.Add(-before),event.Time.Add(after)
+	now:=time.Now()
+	if now.Before(opens){
+		return fmt.Errorf("check-in opens at %s",opens.In(event.Location()).Format("02.01.2006 15:04 MST"))
+	}
+	if now.After(closes){
+		return errors.New("check-in is closed")
+	}
+	member,err:=es.EventRepository.IsMember(ctx,callerId,id)
+	if err!=nil{
+		return err
+	}
+	if !member{
+		return errors.New("user is not a member of the event")
+	}
+	checked,err:=es.EventRepository.CheckIn(ctx,callerId,id,now)
+	if err!=nil{
+		return err
+	}
+	if !checked{
+		return errors.New("user already checked in")
+	}
+	return nil
+}
+
+// CloseAttendance flags no-shows of events whose check-in window has closed
+// and updates the reliability of their members. It returns how many members
+// were affected.
+func (es *eventService) CloseAttendance(ctx context.Context, now time.Time) (int, error){
+	_,after:=es.checkInWindow()
+	res,err:=es.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		members,err:=es.EventRepository.CloseAttendance(c,now.Add(-after))
+		if err!=nil{
+			return nil,err
+		}
+		if err:=es.UserRepository.UpdateReliability(c,members);err!=nil{
+			return nil,err
+		}
+		return len(members),nil
+	})
+	if err!=nil{
+		return 0,err
+	}
+	return res.(int),nil
+}
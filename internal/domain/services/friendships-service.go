@@ -5,11 +5,13 @@ import (
 	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
+	"crap/internal/locales"
 )
 
 type FriendshipsService interface {
 	AddFriend(ctx context.Context, req dto.AddFriendRequest) error
-	GetFriends(ctx context.Context, req dto.GetFriendsRequest) ([]entities.User, error)
+	GetFriends(ctx context.Context, req dto.GetFriendsRequest) (*query.Page[entities.User], error)
 	CancelFriendship(ctx context.Context, req dto.CancelFriendshipRequest) error
 	AcceptFriendship(ctx context.Context, req dto.AcceptFriendshipRequest) error
 	GetFriendRequests(ctx context.Context, req dto.GetFriendsReqRequests) ([]entities.User, error)
@@ -18,12 +20,16 @@ type FriendshipsService interface {
 type friendshipsService struct{
 	FriendshipsRepository repositories.FriendshipsRepository
 	UserRepository repositories.UserRepository
+	NotificationService NotificationService
+	Transactor repositories.Transactor
 }
 
-func NewFriendshipsService(fr repositories.FriendshipsRepository, ur repositories.UserRepository) FriendshipsService{
+func NewFriendshipsService(fr repositories.FriendshipsRepository, ur repositories.UserRepository, ns NotificationService, t repositories.Transactor) FriendshipsService{
 	return &friendshipsService{
 		FriendshipsRepository: fr,
 		UserRepository: ur,
+		NotificationService: ns,
+		Transactor: t,
 	}
 }
 
@@ -36,30 +42,49 @@ func(fr *friendshipsService) AddFriend(ctx context.Context, req dto.AddFriendReq
 	if err!=nil{
 		return err
 	}
-	if err:=fr.FriendshipsRepository.Add(ctx,user1.Id.String(),user2.Id.String());err!=nil{
+	_,err=fr.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		if err:=fr.FriendshipsRepository.Add(c,user1.Id.String(),user2.Id.String());err!=nil{
+			return nil,err
+		}
+		msg:=entities.Message{Key: locales.FriendRequest, Params: entities.MessageParams{Login: user1.Login}}
+		if err:=fr.NotificationService.NotifyUser(c,entities.Event{},user2.Id.String(),entities.NotificationFriend,msg);err!=nil{
+			return nil,err
+		}
+		return nil,nil
+	})
+	if err!=nil{
 		return err
 	}
 	return nil
 }
 
-func(fr *friendshipsService) GetFriends(ctx context.Context, req dto.GetFriendsRequest) ([]entities.User, error){
+func(fr *friendshipsService) GetFriends(ctx context.Context, req dto.GetFriendsRequest) (*query.Page[entities.User], error){
 	user,err:=fr.UserRepository.FindById(ctx,req.UserId)
 	if err!=nil{
 		return nil,err
 	}
-	friendsId,err:=fr.FriendshipsRepository.Fetch(ctx,user.Id.String(),req.Amount,req.Page)
+	w,err:=window(req.CursorRequest)
 	if err!=nil{
 		return nil,err
 	}
-	friends:=[]entities.User{}
-	for _,fid:=range friendsId{
+	friendsId,err:=fr.FriendshipsRepository.Fetch(ctx,user.Id.String(),w)
+	if err!=nil{
+		return nil,err
+	}
+	friends:=query.Page[entities.User]{
+		Items: []entities.User{},
+		NextCursor: friendsId.NextCursor,
+		PrevCursor: friendsId.PrevCursor,
+		HasMore: friendsId.HasMore,
+	}
+	for _,fid:=range friendsId.Items{
 		friend,err:=fr.UserRepository.FindById(ctx,fid)
 		if err!=nil{
 			return nil,err
 		}
-		friends=append(friends, *friend)
+		friends.Items=append(friends.Items, *friend)
 	}
-	return friends,nil
+	return &friends,nil
 }
 
 func(fr *friendshipsService) CancelFriendship(ctx context.Context, req dto.CancelFriendshipRequest) error{
@@ -5,13 +5,14 @@ import (
 	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
 	"slices"
 )
 
 type GameService interface {
 	AddGameToUser(ctx context.Context, req dto.AddGameRequest) error
 	GetByName(ctx context.Context, name string) (*entities.Game, error)
-	FetchGames(ctx context.Context, req dto.PaginationRequest) ([]entities.Game, error)
+	FetchGames(ctx context.Context, req dto.CursorRequest) (*query.Page[entities.Game], error)
 	DeleteGame(ctx context.Context, req dto.DeleteGameRequest) error
 	GetSorted(ctx context.Context, req dto.GamesSortRequest) ([]entities.Game, error)
 	GetFiltered(ctx context.Context, req dto.GamesFilterRequest) ([]entities.Game, error)
@@ -66,8 +67,12 @@ func (gs *gameService) GetByName(ctx context.Context, name string) (*entities.Ga
 	return game,nil
 }
 
-func (gs *gameService) FetchGames(ctx context.Context, req dto.PaginationRequest) ([]entities.Game, error){
-	games,err:=gs.GameRepository.Fetch(ctx,req.Amount,req.Page)
+func (gs *gameService) FetchGames(ctx context.Context, req dto.CursorRequest) (*query.Page[entities.Game], error){
+	w,err:=window(req)
+	if err!=nil{
+		return nil,err
+	}
+	games,err:=gs.GameRepository.Fetch(ctx,w)
 	if err!=nil{
 		return nil,err
 	}
@@ -105,7 +110,7 @@ func (gs *gameService) DeleteGame(ctx context.Context, req dto.DeleteGameRequest
 }
 
 func (gs *gameService) GetSorted(ctx context.Context, req dto.GamesSortRequest) ([]entities.Game, error){
-	games,err:=gs.GameRepository.Sort(ctx,req.Field,req.Direction,req.Amount,req.Page)
+	games,err:=gs.GameRepository.Sort(ctx,query.ParseOrder(req.Field,req.Direction),req.Amount,req.Page)
 	if err!=nil{
 		return nil,err
 	}
@@ -113,7 +118,7 @@ func (gs *gameService) GetSorted(ctx context.Context, req dto.GamesSortRequest)
 }
 
 func (gs *gameService) GetFiltered(ctx context.Context, req dto.GamesFilterRequest) ([]entities.Game, error){
-	games,err:=gs.GameRepository.Filter(ctx,req.Name,req.Amount,req.Page)
+	games,err:=gs.GameRepository.Filter(ctx,splitList(req.Name),req.Amount,req.Page)
 	if err!=nil{
 		return nil,err
 	}
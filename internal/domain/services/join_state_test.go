@@ -0,0 +1,33 @@
+//go:build integration
+
+package services
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"testing"
+)
+
+func TestJoinRejectsClosedEvents(t *testing.T) {
+	env := newStressEnv(t)
+	ctx := context.Background()
+	id := env.event.Id.String()
+	if _, err := env.pool.Exec(ctx, "UPDATE events SET invite_code = $1 WHERE id = $2", "stress-"+id, id); err != nil {
+		t.Fatalf("set invite code: %v", err)
+	}
+	user := env.users[0]
+	for _, state := range []string{entities.EventInProgress, entities.EventFinished, entities.EventCancelled} {
+		if _, err := env.pool.Exec(ctx, "UPDATE events SET state = $1 WHERE id = $2", state, id); err != nil {
+			t.Fatalf("set state %q: %v", state, err)
+		}
+		if res, err := env.join(ctx, user); err == nil {
+			t.Errorf("join in state %q: want an error, got %+v", state, res)
+		}
+		if res, err := env.service.JoinByInvite(ctx, "stress-"+id, "", user); err == nil {
+			t.Errorf("invite join in state %q: want an error, got %+v", state, res)
+		}
+		if member, position := env.state(t, ctx, user); member || position != 0 {
+			t.Errorf("state %q: user got in, member %v, position %d", state, member, position)
+		}
+	}
+}
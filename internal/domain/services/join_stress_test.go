@@ -0,0 +1,292 @@
+//go:build integration
+
+package services
+
+import (
+	"context"
+	"crap/config"
+	"crap/internal/domain/entities"
+	"crap/internal/domain/repositories"
+	"crap/internal/dto"
+	db "crap/internal/infrastructure/db/postgres"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The stress test runs against a migrated database reached through the same
+// PG* variables as the app:
+//
+//	PGHOST=localhost PGPORT=5432 POSTGRES_DB=crap POSTGRES_USER=crap POSTGRES_PASSWORD=crap \
+//		go test -tags integration -run TestJoinUnderLoad ./internal/domain/services
+
+const (
+	stressMax    = 3
+	stressUsers  = 12
+	stressRounds = 20
+)
+
+type stressEnv struct {
+	pool    *pgxpool.Pool
+	events  repositories.EventRepository
+	service EventService
+	event   entities.Event
+	users   []string
+}
+
+func newStressEnv(t *testing.T) *stressEnv {
+	t.Helper()
+	if os.Getenv("PGHOST") == "" {
+		t.Skip("PGHOST is not set")
+	}
+	cfg := &config.Config{Postgres: config.PostgresCfg{
+		Host:     os.Getenv("PGHOST"),
+		Port:     os.Getenv("PGPORT"),
+		Database: os.Getenv("POSTGRES_DB"),
+		User:     os.Getenv("POSTGRES_USER"),
+		Password: os.Getenv("POSTGRES_PASSWORD"),
+		MaxConns: stressUsers,
+	}}
+	pool, err := db.Connect(cfg)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	transactor := repositories.NewTransactor(pool)
+	userRepository := repositories.NewUserRepository(pool, nil)
+	eventRepository := repositories.NewEventRepository(pool, nil)
+	reminderRepository := repositories.NewReminderRepository(pool)
+	notificationService := NewNotificationService(repositories.NewNoticeRepository(pool, nil), eventRepository, userRepository, reminderRepository, repositories.NewOutboxRepository(pool), repositories.NewChannelRepository(pool), repositories.NewStreamRepository(nil), repositories.NewDigestRepository(pool), transactor, cfg)
+	env := &stressEnv{
+		pool:    pool,
+		events:  eventRepository,
+		service: NewEventService(eventRepository, userRepository, repositories.NewGameRepository(pool), repositories.NewFriendshipsRepository(pool), reminderRepository, repositories.NewTemplateRepository(pool), notificationService, transactor, cfg),
+	}
+
+	ctx := context.Background()
+	prefix := "stress-" + uuid.NewString()[:8]
+	for i := 0; i <= stressUsers; i++ {
+		user := entities.User{
+			Id:             uuid.New(),
+			Login:          fmt.Sprintf("%s-%d", prefix, i),
+			Telegram:       fmt.Sprintf("%s-%d", prefix, i),
+			Password:       []byte("stress"),
+			DateOfRegister: time.Now(),
+			TimeZone:       "UTC",
+		}
+		if err := userRepository.Create(ctx, user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		env.users = append(env.users, user.Id.String())
+	}
+	created := env.users
+	t.Cleanup(func() {
+		if _, err := pool.Exec(context.Background(), "DELETE FROM users WHERE id = ANY($1)", created); err != nil {
+			t.Errorf("delete users: %v", err)
+		}
+	})
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	author := env.users[0]
+	env.users = env.users[1:]
+	env.event = entities.Event{
+		Id:         uuid.New(),
+		AuthorId:   uuid.MustParse(author),
+		Body:       prefix,
+		Game:       "stress",
+		Max:        stressMax,
+		Time:       start,
+		TimeZone:   "UTC",
+		State:      entities.EventScheduled,
+		EndTime:    start.Add(2 * time.Hour),
+		Visibility: entities.VisibilityPublic,
+	}
+	env.event.SeriesId = env.event.Id
+	if err := eventRepository.Create(ctx, env.event, ""); err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := eventRepository.Delete(context.Background(), env.event); err != nil {
+			t.Errorf("delete event: %v", err)
+		}
+	})
+	// The author leaves so every slot is contested.
+	if err := env.service.Unjoin(ctx, dto.UnjoinFromEventRequest{UserId: author, EventId: env.event.Id.String()}); err != nil {
+		t.Fatalf("author unjoin: %v", err)
+	}
+	return env
+}
+
+func (env *stressEnv) join(ctx context.Context, id string) (*dto.JoinEventResponse, error) {
+	return env.service.Join(ctx, dto.JoinToEventRequest{UserId: id, EventId: env.event.Id.String()})
+}
+
+func (env *stressEnv) unjoin(ctx context.Context, id string) error {
+	return env.service.Unjoin(ctx, dto.UnjoinFromEventRequest{UserId: id, EventId: env.event.Id.String()})
+}
+
+// parallel runs fn for every user at once and fails on the first error.
+func (env *stressEnv) parallel(t *testing.T, users []string, fn func(id string) error) {
+	t.Helper()
+	start := make(chan struct{})
+	errs := make(chan error, len(users))
+	var wg sync.WaitGroup
+	for _, id := range users {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			<-start
+			if err := fn(id); err != nil {
+				errs <- fmt.Errorf("user %v: %w", id, err)
+			}
+		}(id)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+}
+
+// watch polls the member count in the background. The returned func stops
+// it and returns the largest count it saw.
+func (env *stressEnv) watch(t *testing.T) func() int {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	var peak atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			count, err := env.events.CountMembers(ctx, env.event.Id.String())
+			if err != nil {
+				if ctx.Err() == nil {
+					t.Errorf("count members: %v", err)
+				}
+				return
+			}
+			if int64(count) > peak.Load() {
+				peak.Store(int64(count))
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	stop := func() int {
+		cancel()
+		<-done
+		return int(peak.Load())
+	}
+	t.Cleanup(func() { stop() })
+	return stop
+}
+
+func (env *stressEnv) state(t *testing.T, ctx context.Context, id string) (bool, int) {
+	t.Helper()
+	member, err := env.events.IsMember(ctx, id, env.event.Id.String())
+	if err != nil {
+		t.Fatalf("is member: %v", err)
+	}
+	position, err := env.events.WaitlistPosition(ctx, id, env.event.Id.String())
+	if err != nil {
+		t.Fatalf("waitlist position: %v", err)
+	}
+	return member, position
+}
+
+func TestJoinUnderLoad(t *testing.T) {
+	env := newStressEnv(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	peak := env.watch(t)
+
+	// Everybody joins at once: the first stressMax get in, the rest queue up
+	// with distinct positions.
+	var mu sync.Mutex
+	joined := []string{}
+	waitlist := make([]string, stressUsers-stressMax)
+	env.parallel(t, env.users, func(id string) error {
+		res, err := env.join(ctx, id)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch res.Status {
+		case "joined":
+			joined = append(joined, id)
+		case "waitlisted":
+			if res.Position < 1 || res.Position > len(waitlist) || waitlist[res.Position-1] != "" {
+				return fmt.Errorf("unexpected waitlist position %d", res.Position)
+			}
+			waitlist[res.Position-1] = id
+		default:
+			return fmt.Errorf("unexpected status %q", res.Status)
+		}
+		return nil
+	})
+	if len(joined) != stressMax {
+		t.Fatalf("joined %d users, want %d", len(joined), stressMax)
+	}
+
+	// The members leave at once: the freed slots go to the head of the
+	// waitlist and the rest move up in the same order.
+	env.parallel(t, joined, func(id string) error {
+		return env.unjoin(ctx, id)
+	})
+	for i, id := range waitlist {
+		member, position := env.state(t, ctx, id)
+		if i < stressMax && (!member || position != 0) {
+			t.Errorf("waitlisted user %d was not promoted", i+1)
+		}
+		if i >= stressMax && (member || position != i+1-stressMax) {
+			t.Errorf("waitlisted user %d: member %v, position %d, want position %d", i+1, member, position, i+1-stressMax)
+		}
+	}
+	env.parallel(t, waitlist, func(id string) error {
+		return env.unjoin(ctx, id)
+	})
+
+	// Everybody joins and leaves over and over.
+	env.parallel(t, env.users, func(id string) error {
+		for r := 0; r < stressRounds; r++ {
+			res, err := env.join(ctx, id)
+			if err != nil {
+				return err
+			}
+			if res.Status != "joined" && res.Status != "waitlisted" {
+				return fmt.Errorf("unexpected status %q", res.Status)
+			}
+			if err := env.unjoin(ctx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if n := peak(); n > stressMax {
+		t.Errorf("event had %d members, max is %d", n, stressMax)
+	}
+
+	count, err := env.events.CountMembers(ctx, env.event.Id.String())
+	if err != nil {
+		t.Fatalf("count members: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("event has %d members after everybody left", count)
+	}
+	for _, id := range env.users {
+		if member, position := env.state(t, ctx, id); member || position != 0 {
+			t.Errorf("user %v is still in the event: member %v, position %d", id, member, position)
+		}
+	}
+}
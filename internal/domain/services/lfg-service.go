@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"crap/config"
+	"crap/internal/domain/entities"
+	"crap/internal/domain/repositories"
+	"crap/internal/dto"
+	"crap/internal/locales"
+	"errors"
+	"slices"
+	"time"
+)
+
+type LfgService interface {
+	Enqueue(ctx context.Context, req dto.EnqueueLfgRequest, callerId string) (*entities.LfgTicket, error)
+	Leave(ctx context.Context, callerId string) error
+	Status(ctx context.Context, callerId string) (*dto.LfgStatusResponse, error)
+	Match(ctx context.Context, now time.Time) ([]entities.Event, error)
+}
+
+type lfgService struct {
+	LfgRepository       repositories.LfgRepository
+	UserRepository      repositories.UserRepository
+	GameRepository      repositories.GameRepository
+	EventService        EventService
+	NotificationService NotificationService
+	Transactor          repositories.Transactor
+	Config              *config.Config
+}
+
+func NewLfgService(
+	lr repositories.LfgRepository,
+	ur repositories.UserRepository,
+	gr repositories.GameRepository,
+	eventService EventService,
+	notificationService NotificationService,
+	t repositories.Transactor,
+	cfg *config.Config) LfgService {
+	return &lfgService{
+		LfgRepository:       lr,
+		UserRepository:      ur,
+		GameRepository:      gr,
+		EventService:        eventService,
+		NotificationService: notificationService,
+		Transactor:          t,
+		Config:              cfg,
+	}
+}
+
+func (ls *lfgService) Enqueue(ctx context.Context, req dto.EnqueueLfgRequest, callerId string) (*entities.LfgTicket, error){
+	if req.MaxRating > 0 && req.MaxRating < req.MinRating{
+		return nil,errors.New("max rating cannot be lower than min rating")
+	}
+	user,err:=ls.UserRepository.FindById(ctx,callerId)
+	if err!=nil{
+		return nil,err
+	}
+	game,err:=ls.GameRepository.FindById(ctx,req.Game)
+	if err!=nil{
+		return nil,err
+	}
+	if !slices.Contains(user.Games,game.Id){
+		return nil,errors.New("user does not have this game")
+	}
+	ticket:=entities.LfgTicket{
+		UserId: user.Id,
+		Game: game.Id,
+		PartySize: req.PartySize,
+		MinRating: req.MinRating,
+		MaxRating: req.MaxRating,
+		Rating: user.Rating,
+		Time: time.Now(),
+	}
+	if err:=ls.LfgRepository.Enqueue(ctx,ticket);err!=nil{
+		return nil,err
+	}
+	return &ticket,nil
+}
+
+func (ls *lfgService) Leave(ctx context.Context, callerId string) error{
+	removed,err:=ls.LfgRepository.Dequeue(ctx,callerId)
+	if err!=nil{
+		return err
+	}
+	if !removed{
+		return errors.New("user is not in the queue")
+	}
+	return nil
+}
+
+func (ls *lfgService) Status(ctx context.Context, callerId string) (*dto.LfgStatusResponse, error){
+	ticket,err:=ls.LfgRepository.FindByUser(ctx,callerId)
+	if err!=nil{
+		return nil,err
+	}
+	status:=dto.LfgStatusResponse{Ticket: ticket}
+	if ticket == nil{
+		return &status,nil
+	}
+	tickets,err:=ls.LfgRepository.Fetch(ctx)
+	if err!=nil{
+		return nil,err
+	}
+	for _,other:=range tickets{
+		if other.Game == ticket.Game && other.PartySize == ticket.PartySize{
+			status.Waiting++
+		}
+	}
+	return &status,nil
+}
+
+// Match groups compatible players, oldest tickets first, and turns every
+// full group into an invite-only event authored by the player who waited
+// longest. Stale tickets are dropped from the queue. A group whose event
+// cannot be created goes back to the queue and its error is returned along
+// with the events that were created.
+func (ls *lfgService) Match(ctx context.Context, now time.Time) ([]entities.Event, error){
+	tickets,err:=ls.LfgRepository.Fetch(ctx)
+	if err!=nil{
+		return nil,err
+	}
+	slices.SortFunc(tickets,func(a, b entities.LfgTicket) int {
+		return a.Time.Compare(b.Time)
+	})
+	ttl:=ls.Config.Lfg.TicketTtl
+	if ttl <= 0{
+		ttl = time.Hour
+	}
+	events:=[]entities.Event{}
+	failed:=[]error{}
+	used:=map[int]bool{}
+	for i,anchor:=range tickets{
+		if used[i]{
+			continue
+		}
+		if anchor.Time.Add(ttl).Before(now){
+			if _,err:=ls.LfgRepository.Dequeue(ctx,anchor.UserId.String());err!=nil{
+				return nil,err
+			}
+			used[i] = true
+			continue
+		}
+		group:=[]entities.LfgTicket{anchor}
+		picked:=[]int{i}
+		for j:=i+1;j<len(tickets) && len(group) < anchor.PartySize;j++{
+			if used[j] || tickets[j].Time.Add(ttl).Before(now){
+				continue
+			}
+			if !slices.ContainsFunc(group,func(t entities.LfgTicket) bool { return !t.Matches(tickets[j]) }){
+				group=append(group, tickets[j])
+				picked=append(picked, j)
+			}
+		}
+		if len(group) < anchor.PartySize{
+			continue
+		}
+		claimed,err:=ls.LfgRepository.Claim(ctx,group)
+		if err!=nil{
+			return nil,err
+		}
+		if !claimed{
+			continue
+		}
+		for _,j:=range picked{
+			used[j] = true
+		}
+		event,err:=ls.createMatch(ctx,group,now)
+		if err!=nil{
+			failed=append(failed, err)
+			for _,ticket:=range group{
+				if err:=ls.LfgRepository.Enqueue(ctx,ticket);err!=nil{
+					return nil,err
+				}
+			}
+			continue
+		}
+		events=append(events, *event)
+	}
+	return events,errors.Join(failed...)
+}
+
+// createMatch creates the event, joins the rest of the group and queues the
+// notification in one transaction, so a failed join leaves no event behind
+// when the tickets go back to the queue.
+func (ls *lfgService) createMatch(ctx context.Context, group []entities.LfgTicket, now time.Time) (*entities.Event, error){
+	author,err:=ls.UserRepository.FindById(ctx,group[0].UserId.String())
+	if err!=nil{
+		return nil,err
+	}
+	zone:=author.TimeZone
+	if zone == ""{
+		zone = "UTC"
+	}
+	delay:=max(ls.Config.Lfg.StartDelay,ls.Config.Event.MinLead+time.Minute)
+	res,err:=ls.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		event,err:=ls.EventService.CreateEvent(c,dto.CreateEventRequest{
+			AuthorId: author.Id.String(),
+			Game: group[0].Game,
+			Body: "LFG",
+			Max: group[0].PartySize,
+			StartAt: now.Add(delay).Format(time.RFC3339),
+			TimeZone: zone,
+			Visibility: entities.VisibilityInvite,
+		})
+		if err!=nil{
+			return nil,err
+		}
+		for _,ticket:=range group[1:]{
+			if _,err:=ls.EventService.JoinByInvite(c,event.InviteCode,"",ticket.UserId.String());err!=nil{
+				return nil,err
+			}
+		}
+		msg:=eventMessage(ls.Config,locales.LfgMatched,*event)
+		if err:=ls.NotificationService.CreateNotification(c,*event,entities.NotificationLfg,msg);err!=nil{
+			return nil,err
+		}
+		return event,nil
+	})
+	if err!=nil{
+		return nil,err
+	}
+	return res.(*entities.Event),nil
+}
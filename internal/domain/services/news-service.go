@@ -6,6 +6,7 @@ import (
 	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
 	"errors"
 	"fmt"
 	"io"
@@ -18,7 +19,7 @@ import (
 type NewsService interface {
 	CreateNews(ctx context.Context, req dto.CreateNewsRequest) (*entities.News, error)
 	GetById(ctx context.Context, id string) (*entities.News, error)
-	FetchNews(ctx context.Context, req dto.PaginationRequest) ([]entities.News, error)
+	FetchNews(ctx context.Context, req dto.CursorRequest) (*query.Page[entities.News], error)
 }
 
 type newsService struct {
@@ -105,8 +106,12 @@ func (ns *newsService) GetById(ctx context.Context, id string) (*entities.News,
 	return news,nil
 }
 
-func (ns *newsService) FetchNews(ctx context.Context, req dto.PaginationRequest) ([]entities.News, error){
-	news,err:=ns.NewsRepository.Fetch(ctx,req.Amount,req.Page)
+func (ns *newsService) FetchNews(ctx context.Context, req dto.CursorRequest) (*query.Page[entities.News], error){
+	w,err:=window(req)
+	if err!=nil{
+		return nil,err
+	}
+	news,err:=ns.NewsRepository.Fetch(ctx,w)
 	if err!=nil{
 		return nil,err
 	}
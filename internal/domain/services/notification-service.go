@@ -2,64 +2,167 @@ package services
 
 import (
 	"context"
+	"crap/config"
 	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
+	"crap/internal/locales"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type NotificationService interface {
-	CreateNotification(ctx context.Context, event entities.Event, msg string) error
+	CreateNotification(ctx context.Context, event entities.Event, kind string, msg entities.Message) error
+	NotifyUser(ctx context.Context, event entities.Event, id, kind string, msg entities.Message) error
 	DeleteNotification(ctx context.Context, id, nid string) error
-	FetchNotifications(ctx context.Context, req dto.GetNotificationsRequest) ([]entities.Notification, error)
+	FetchNotifications(ctx context.Context, req dto.GetNotificationsRequest) (*query.Page[entities.Notification], error)
 	DeleteAllNotifications(ctx context.Context, id string) error
+	FetchDueReminders(ctx context.Context, now time.Time) ([]entities.Reminder, error)
+	SendReminder(ctx context.Context, reminder entities.Reminder, now time.Time) (bool, error)
+	AnnounceStart(ctx context.Context, event entities.Event) error
+	GetPreferences(ctx context.Context, id string) ([]entities.ChannelPreference, error)
+	SetPreference(ctx context.Context, req dto.SetChannelPreferenceRequest, callerId string) error
+	SetEmail(ctx context.Context, req dto.SetEmailRequest, callerId string) error
+	SetWebhook(ctx context.Context, req dto.SetWebhookRequest, callerId string) (*dto.WebhookResponse, error)
+	DeleteContact(ctx context.Context, channel, callerId string) error
+	Stream(ctx context.Context, id, lastId string, failed func(error)) (<-chan entities.Notification, error)
+	MarkRead(ctx context.Context, req dto.MarkNotificationsReadRequest, callerId string) (int64, error)
+	CountUnread(ctx context.Context, callerId string) (int, error)
+	GetSchedule(ctx context.Context, id string) (*entities.NotificationSchedule, error)
+	SetSchedule(ctx context.Context, req dto.SetScheduleRequest, callerId string) error
+	SendDigests(ctx context.Context, now time.Time) (int, error)
+	Deliverer
+	Committer
 }
 
 type notificationService struct {
 	NotificationRepository repositories.NotificationRepository
 	EventRepository        repositories.EventRepository
 	UserRepository         repositories.UserRepository
+	ReminderRepository     repositories.ReminderRepository
+	OutboxRepository       repositories.OutboxRepository
+	ChannelRepository      repositories.ChannelRepository
+	StreamRepository       repositories.StreamRepository
+	DigestRepository       repositories.DigestRepository
 	Transactor             repositories.Transactor
+	Config                 *config.Config
 }
 
 func NewNotificationService(
 	nr repositories.NotificationRepository,
 	er repositories.EventRepository,
 	ur repositories.UserRepository,
-	t repositories.Transactor) NotificationService {
+	rr repositories.ReminderRepository,
+	or repositories.OutboxRepository,
+	cr repositories.ChannelRepository,
+	sr repositories.StreamRepository,
+	dr repositories.DigestRepository,
+	t repositories.Transactor,
+	cfg *config.Config) NotificationService {
 	return &notificationService{
 		NotificationRepository: nr,
 		EventRepository:  er,
 		UserRepository:   ur,
+		ReminderRepository: rr,
+		OutboxRepository: or,
+		ChannelRepository: cr,
+		StreamRepository: sr,
+		DigestRepository: dr,
 		Transactor:       t,
+		Config:           cfg,
 	}
 }
 
-func (ns *notificationService) CreateNotification(ctx context.Context, event entities.Event, msg string) error{
-	_,err:=ns.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
-		notification:=entities.Notification{
-			Id: uuid.New(),
-			EventId: event.Id,
-			Body: msg,
-			Time: time.Now(),
-		}
-		if err:=ns.NotificationRepository.Create(ctx, notification);err!=nil{
-			return nil,err
-		}
-		members,err:=ns.EventRepository.FetchMembers(c,event.Id.String())
-		if err!=nil{
-			return nil,err
-		}
-		for _,id:=range members{
-			user,err:=ns.UserRepository.FindById(c,id)
+// defaultReminder is the lead time in minutes used for users who have not
+// chosen their own reminders.
+func defaultReminder(cfg *config.Config) int {
+	if cfg.Event.DefaultReminder > 0 {
+		return int(cfg.Event.DefaultReminder.Minutes())
+	}
+	return 10
+}
+
+// streamBacklog caps how many missed notifications a resumed stream replays.
+const streamBacklog = 100
+
+// eventMessage fills the placeholders every event message can use: the
+// title, the game, the start and the link to the event page.
+func eventMessage(cfg *config.Config, key string, event entities.Event) entities.Message{
+	msg:=entities.Message{
+		Key: key,
+		Params: entities.MessageParams{Title: event.Body, Game: event.Game, Time: event.Time},
+	}
+	if cfg.App.Url != ""{
+		msg.Params.Link = strings.TrimSuffix(cfg.App.Url,"/")+"/events/"+event.Id.String()
+	}
+	return msg
+}
+
+// CreateNotification queues the message for every member of the event. It
+// reaches them through the outbox once the surrounding transaction commits.
+func (ns *notificationService) CreateNotification(ctx context.Context, event entities.Event, kind string, msg entities.Message) error{
+	return ns.enqueue(ctx,uuid.NewString(),kind,event,"",msg,false)
+}
+
+func (ns *notificationService) NotifyUser(ctx context.Context, event entities.Event, id, kind string, msg entities.Message) error{
+	return ns.enqueue(ctx,uuid.NewString(),kind,event,id,msg,false)
+}
+
+// AnnounceStart queues the start message with the check-in button. It is
+// keyed by the event, so the members hear about the start once.
+func (ns *notificationService) AnnounceStart(ctx context.Context, event entities.Event) error{
+	msg:=eventMessage(ns.Config,locales.Start,event)
+	return ns.enqueue(ctx,"start:"+event.Id.String(),entities.NotificationStart,event,"",msg,true)
+}
+
+// enqueue writes the in-app notification and a message for every outside
+// channel each recipient picked for the kind to the outbox. Without a user id
+// the recipients are the members of the event. The channel and the recipient
+// are appended to key, so every delivery has its own idempotency key.
+func (ns *notificationService) enqueue(ctx context.Context, key, kind string, event entities.Event, id string, msg entities.Message, checkIn bool) error{
+	body,err:=locales.Render(locales.Default,event.Location(),msg)
+	if err!=nil{
+		return err
+	}
+	_,err=ns.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		recipients:=[]string{id}
+		if id == ""{
+			members,err:=ns.EventRepository.FetchMembers(c,event.Id.String())
 			if err!=nil{
 				return nil,err
 			}
-			if err:=ns.NotificationRepository.CreateForUsers(c,notification,user.Id.String());err!=nil{
+			recipients=members
+		}
+		messages:=[]entities.OutboxMessage{{
+			Id: uuid.New(),
+			Key: key+":"+entities.ChannelNotification,
+			Channel: entities.ChannelNotification,
+			Payload: entities.OutboxPayload{Type: kind, EventId: event.Id, UserId: id, Body: body, Message: &msg},
+		}}
+		for _,recipient:=range recipients{
+			channels,err:=ns.channels(c,recipient,kind)
+			if err!=nil{
 				return nil,err
 			}
+			for _,channel:=range channels{
+				messages=append(messages,entities.OutboxMessage{
+					Id: uuid.New(),
+					Key: key+":"+channel+":"+recipient,
+					Channel: channel,
+					Payload: entities.OutboxPayload{Type: kind, EventId: event.Id, UserId: recipient, Body: body, Message: &msg, CheckIn: checkIn},
+				})
+			}
+		}
+		if err:=ns.OutboxRepository.Enqueue(c,messages...);err!=nil{
+			return nil,err
 		}
 		return nil,nil
 	})
@@ -69,6 +172,167 @@ func (ns *notificationService) CreateNotification(ctx context.Context, event ent
 	return nil
 }
 
+// channels returns the outside channels of the user for the kind, falling
+// back to the defaults. Telegram is left out until a chat is linked.
+func (ns *notificationService) channels(ctx context.Context, id, kind string) ([]string, error){
+	channels,found,err:=ns.ChannelRepository.FetchChannels(ctx,id,kind)
+	if err!=nil{
+		return nil,err
+	}
+	if !found{
+		channels=defaultChannels
+	}
+	if !slices.Contains(channels,entities.ChannelTelegram){
+		return channels,nil
+	}
+	user,err:=ns.UserRepository.FindById(ctx,id)
+	if err!=nil{
+		return nil,err
+	}
+	if !user.HasTelegram(){
+		return slices.DeleteFunc(slices.Clone(channels),func(c string) bool { return c == entities.ChannelTelegram }),nil
+	}
+	return channels,nil
+}
+
+// Deliver is the notification channel of the outbox. The notification takes
+// the id of the outbox message, so a repeated delivery adds nothing.
+func (ns *notificationService) Deliver(ctx context.Context, msg entities.OutboxMessage) error{
+	recipients,err:=ns.recipients(ctx,msg)
+	if err!=nil{
+		return err
+	}
+	notification:=delivered(msg)
+	if err:=ns.NotificationRepository.Create(ctx,notification);err!=nil{
+		return err
+	}
+	for _,id:=range recipients{
+		if err:=ns.NotificationRepository.CreateForUsers(ctx,notification,id);err!=nil{
+			return err
+		}
+	}
+	return nil
+}
+
+// Committed resets the unread counters of the recipients and pushes the
+// stored notification to their open streams. A failed push does not stop the
+// others, the client gets the notification when it resumes or lists them.
+func (ns *notificationService) Committed(ctx context.Context, msg entities.OutboxMessage) error{
+	recipients,err:=ns.recipients(ctx,msg)
+	if err!=nil{
+		return fmt.Errorf("cannot stream notification %v: %w",msg.Id,err)
+	}
+	failed:=[]error{}
+	if err:=ns.NotificationRepository.ForgetUnread(ctx,recipients...);err!=nil{
+		failed=append(failed, fmt.Errorf("cannot reset unread counters for %v: %w",msg.Id,err))
+	}
+	for _,id:=range recipients{
+		user,err:=ns.UserRepository.FindById(ctx,id)
+		if err!=nil{
+			failed=append(failed, fmt.Errorf("cannot stream notification %v to %v: %w",msg.Id,id,err))
+			continue
+		}
+		notification:=delivered(msg)
+		if err:=localize(user,&notification);err!=nil{
+			failed=append(failed, fmt.Errorf("cannot stream notification %v to %v: %w",msg.Id,id,err))
+			continue
+		}
+		if err:=ns.StreamRepository.Publish(ctx,id,notification);err!=nil{
+			failed=append(failed, fmt.Errorf("cannot stream notification %v to %v: %w",msg.Id,id,err))
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// recipients are the user of the message or, without one, the members of
+// the event.
+func (ns *notificationService) recipients(ctx context.Context, msg entities.OutboxMessage) ([]string, error){
+	if msg.Payload.UserId != ""{
+		return []string{msg.Payload.UserId},nil
+	}
+	return ns.EventRepository.FetchMembers(ctx,msg.Payload.EventId.String())
+}
+
+func delivered(msg entities.OutboxMessage) entities.Notification{
+	return entities.Notification{
+		Id: msg.Id,
+		EventId: msg.Payload.EventId,
+		Body: msg.Payload.Body,
+		Time: msg.CreatedAt,
+		Message: msg.Payload.Message,
+	}
+}
+
+// localize renders the notification for the reader. Notifications stored
+// before the catalog keep their body.
+func localize(user *entities.User, n *entities.Notification) error{
+	if n.Message == nil{
+		return nil
+	}
+	body,err:=locales.Render(user.Language,user.Location(),*n.Message)
+	if err!=nil{
+		return err
+	}
+	n.Body = body
+	return nil
+}
+
+// Stream returns the notifications of the user as they are delivered. With
+// lastId it first replays the ones stored after that notification, so a
+// client that lost the connection picks up where it stopped. The channel is
+// closed when ctx is done. Notifications that cannot be read are skipped and
+// handed to failed.
+func (ns *notificationService) Stream(ctx context.Context, id, lastId string, failed func(error)) (<-chan entities.Notification, error){
+	user,err:=ns.UserRepository.FindById(ctx,id)
+	if err!=nil{
+		return nil,err
+	}
+	live,err:=ns.StreamRepository.Subscribe(ctx,id,failed)
+	if err!=nil{
+		return nil,err
+	}
+	missed:=[]entities.Notification{}
+	if lastId != ""{
+		missed,err=ns.NotificationRepository.FetchAfter(ctx,id,lastId,streamBacklog)
+		if err!=nil{
+			return nil,err
+		}
+	}
+	for i:=range missed{
+		// a notification that cannot be rendered is replayed with the
+		// body it was stored with
+		if err:=localize(user,&missed[i]);err!=nil && failed != nil{
+			failed(err)
+		}
+	}
+	out:=make(chan entities.Notification)
+	go func() {
+		defer close(out)
+		// the subscription is open before the replay is read, a
+		// notification may come through both
+		sent:=map[uuid.UUID]bool{}
+		for _,n:=range missed{
+			sent[n.Id]=true
+			select{
+			case out<-n:
+			case <-ctx.Done():
+				return
+			}
+		}
+		for n:=range live{
+			if sent[n.Id]{
+				continue
+			}
+			select{
+			case out<-n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out,nil
+}
+
 func (ns *notificationService) DeleteNotification(ctx context.Context, id, nid string) error{
 	_,err:=ns.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
 		user,err:=ns.UserRepository.FindById(ctx,id)
@@ -87,18 +351,31 @@ func (ns *notificationService) DeleteNotification(ctx context.Context, id, nid s
 	if err!=nil{
 		return err
 	}
+	if err:=ns.NotificationRepository.ForgetUnread(ctx,id);err!=nil{
+		return err
+	}
 	return nil
 }
 
-func (ns *notificationService) FetchNotifications(ctx context.Context, req dto.GetNotificationsRequest) ([]entities.Notification, error){
+func (ns *notificationService) FetchNotifications(ctx context.Context, req dto.GetNotificationsRequest) (*query.Page[entities.Notification], error){
 	user,err:=ns.UserRepository.FindById(ctx,req.UserId)
 	if err!=nil{
 		return nil,err
 	}
-	notifications,err:=ns.NotificationRepository.Fetch(ctx,user.Id.String(),req.Amount,req.Page)
+	w,err:=window(req.CursorRequest)
+	if err!=nil{
+		return nil,err
+	}
+	filter:=repositories.NotificationFilter{Read: req.Read, EventId: req.EventId}
+	notifications,err:=ns.NotificationRepository.Fetch(ctx,user.Id.String(),filter,w)
 	if err!=nil{
 		return nil,err
 	}
+	for i:=range notifications.Items{
+		if err:=localize(user,&notifications.Items[i]);err!=nil{
+			return nil,err
+		}
+	}
 	return notifications,nil
 }
 
@@ -110,5 +387,249 @@ func (ns *notificationService) DeleteAllNotifications(ctx context.Context, id st
 	if err:=ns.NotificationRepository.DeleteAll(ctx,user.Id.String());err!=nil{
 		return err
 	}
+	if err:=ns.NotificationRepository.ForgetUnread(ctx,user.Id.String());err!=nil{
+		return err
+	}
+	return nil
+}
+
+// MarkRead marks the listed notifications of the caller read, or all of them
+// up to req.Before, and returns how many were unread.
+func (ns *notificationService) MarkRead(ctx context.Context, req dto.MarkNotificationsReadRequest, callerId string) (int64, error){
+	before,err:=parseFilterTime(req.Before)
+	if err!=nil{
+		return 0,err
+	}
+	var marked int64
+	if len(req.Ids) > 0{
+		marked,err=ns.NotificationRepository.MarkRead(ctx,callerId,req.Ids,time.Now())
+	}else{
+		marked,err=ns.NotificationRepository.MarkReadBefore(ctx,callerId,before,time.Now())
+	}
+	if err!=nil{
+		return 0,err
+	}
+	if marked > 0{
+		if err:=ns.NotificationRepository.ForgetUnread(ctx,callerId);err!=nil{
+			return 0,err
+		}
+	}
+	return marked,nil
+}
+
+func (ns *notificationService) CountUnread(ctx context.Context, callerId string) (int, error){
+	count,err:=ns.NotificationRepository.CountUnread(ctx,callerId)
+	if err!=nil{
+		return 0,err
+	}
+	return count,nil
+}
+
+func (ns *notificationService) FetchDueReminders(ctx context.Context, now time.Time) ([]entities.Reminder, error){
+	reminders,err:=ns.ReminderRepository.FetchDue(ctx,now,defaultReminder(ns.Config))
+	if err!=nil{
+		return nil,err
+	}
+	return reminders,nil
+}
+
+// SendReminder claims the reminder in the ledger and queues it in the same
+// transaction, so each offset fires once even when several schedulers run.
+// The start time is part of the key, a rescheduled event is reminded again.
+// The time left in the message is counted from now. It reports false when the
+// reminder was already claimed.
+func (ns *notificationService) SendReminder(ctx context.Context, reminder entities.Reminder, now time.Time) (bool, error){
+	msg:=eventMessage(ns.Config,locales.Reminder,reminder.Event)
+	msg.Params.Lead = reminder.Event.Time.Sub(now)
+	res,err:=ns.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		claimed,err:=ns.ReminderRepository.MarkSent(c,reminder)
+		if err!=nil{
+			return nil,err
+		}
+		if !claimed{
+			return false,nil
+		}
+		key:=fmt.Sprintf("reminder:%s:%s:%d:%d",reminder.Event.Id,reminder.UserId,reminder.Event.Time.Unix(),slices.Max(reminder.Offsets))
+		if err:=ns.enqueue(c,key,entities.NotificationReminder,reminder.Event,reminder.UserId.String(),msg,false);err!=nil{
+			return nil,err
+		}
+		return true,nil
+	})
+	if err!=nil{
+		return false,err
+	}
+	return res.(bool),nil
+}
+
+// GetPreferences returns the channels of every notification type, the
+// defaults included.
+func (ns *notificationService) GetPreferences(ctx context.Context, id string) ([]entities.ChannelPreference, error){
+	saved,err:=ns.ChannelRepository.FetchPreferences(ctx,id)
+	if err!=nil{
+		return nil,err
+	}
+	preferences:=make([]entities.ChannelPreference,0,len(entities.NotificationTypes))
+	for _,kind:=range entities.NotificationTypes{
+		preference:=entities.ChannelPreference{Type: kind, Channels: defaultChannels}
+		for _,p:=range saved{
+			if p.Type == kind{
+				preference.Channels=p.Channels
+			}
+		}
+		preferences=append(preferences,preference)
+	}
+	return preferences,nil
+}
+
+func (ns *notificationService) SetPreference(ctx context.Context, req dto.SetChannelPreferenceRequest, callerId string) error{
+	channels:=req.Channels
+	if channels == nil{
+		channels=[]string{}
+	}
+	if err:=ns.ChannelRepository.SavePreference(ctx,callerId,entities.ChannelPreference{Type: req.Type, Channels: channels});err!=nil{
+		return err
+	}
+	return nil
+}
+
+func (ns *notificationService) SetEmail(ctx context.Context, req dto.SetEmailRequest, callerId string) error{
+	user,err:=ns.UserRepository.FindById(ctx,callerId)
+	if err!=nil{
+		return err
+	}
+	if err:=ns.ChannelRepository.SaveContact(ctx,entities.Contact{UserId: user.Id, Channel: entities.ChannelEmail, Address: req.Email});err!=nil{
+		return err
+	}
+	return nil
+}
+
+// SetWebhook stores the url with a new signing secret. The secret is shown
+// only in this response.
+func (ns *notificationService) SetWebhook(ctx context.Context, req dto.SetWebhookRequest, callerId string) (*dto.WebhookResponse, error){
+	user,err:=ns.UserRepository.FindById(ctx,callerId)
+	if err!=nil{
+		return nil,err
+	}
+	b:=make([]byte,32)
+	if _,err:=rand.Read(b);err!=nil{
+		return nil,err
+	}
+	contact:=entities.Contact{UserId: user.Id, Channel: entities.ChannelWebhook, Address: req.Url, Secret: hex.EncodeToString(b)}
+	if err:=ns.ChannelRepository.SaveContact(ctx,contact);err!=nil{
+		return nil,err
+	}
+	return &dto.WebhookResponse{Url: contact.Address, Secret: contact.Secret},nil
+}
+
+func (ns *notificationService) DeleteContact(ctx context.Context, channel, callerId string) error{
+	if err:=ns.ChannelRepository.DeleteContact(ctx,callerId,channel);err!=nil{
+		return err
+	}
+	return nil
+}
+
+func (ns *notificationService) GetSchedule(ctx context.Context, id string) (*entities.NotificationSchedule, error){
+	schedule,err:=ns.ChannelRepository.FetchSchedule(ctx,id)
+	if err!=nil{
+		return nil,err
+	}
+	return schedule,nil
+}
+
+func (ns *notificationService) SetSchedule(ctx context.Context, req dto.SetScheduleRequest, callerId string) error{
+	user,err:=ns.UserRepository.FindById(ctx,callerId)
+	if err!=nil{
+		return err
+	}
+	schedule:=entities.NotificationSchedule{
+		UserId: user.Id,
+		QuietFrom: req.QuietFrom,
+		QuietTo: req.QuietTo,
+		Digest: req.Digest,
+		DigestAt: req.DigestAt,
+		RemindersInQuiet: req.RemindersInQuiet,
+	}
+	if err:=ns.ChannelRepository.SaveSchedule(ctx,schedule);err!=nil{
+		return err
+	}
+	return nil
+}
+
+// SendDigests queues the digests that are due and returns how many. A digest
+// is due at the first full hour or DigestAt after its oldest item and waits
+// out the quiet hours. Items of a user who turned the digest off go out at
+// once.
+func (ns *notificationService) SendDigests(ctx context.Context, now time.Time) (int, error){
+	pending,err:=ns.DigestRepository.Pending(ctx)
+	if err!=nil{
+		return 0,err
+	}
+	sent:=0
+	for _,p:=range pending{
+		user,err:=ns.UserRepository.FindById(ctx,p.UserId.String())
+		if err!=nil{
+			return sent,err
+		}
+		schedule,err:=ns.ChannelRepository.FetchSchedule(ctx,user.Id.String())
+		if err!=nil{
+			return sent,err
+		}
+		due:=p.Since
+		if schedule.Digest != entities.DigestOff{
+			due=schedule.NextDigest(p.Since,user.Location())
+		}
+		if now.Before(due){
+			continue
+		}
+		if _,held:=schedule.Holds(entities.NotificationDigest,now,user.Location());held{
+			continue
+		}
+		if err:=ns.sendDigest(ctx,user,p.Channel,due);err!=nil{
+			return sent,err
+		}
+		sent++
+	}
+	return sent,nil
+}
+
+// sendDigest takes the items of the channel and queues them as one message
+// rendered for the user. The key carries the due time, a digest is queued
+// once even when several schedulers run.
+func (ns *notificationService) sendDigest(ctx context.Context, user *entities.User, channel string, due time.Time) error{
+	_,err:=ns.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		items,err:=ns.DigestRepository.Take(c,user.Id.String(),channel)
+		if err!=nil{
+			return nil,err
+		}
+		if len(items) == 0{
+			return nil,nil
+		}
+		lines:=make([]string,0,len(items))
+		for _,item:=range items{
+			notification:=entities.Notification{Body: item.Payload.Body, Message: item.Payload.Message}
+			if err:=localize(user,&notification);err!=nil{
+				return nil,err
+			}
+			lines=append(lines,notification.Body)
+		}
+		msg:=entities.Message{Key: locales.Digest, Params: entities.MessageParams{Count: len(items), Items: lines}}
+		body,err:=locales.Render(user.Language,user.Location(),msg)
+		if err!=nil{
+			return nil,err
+		}
+		digest:=entities.OutboxMessage{
+			Id: uuid.New(),
+			Key: fmt.Sprintf("digest:%s:%s:%d",user.Id,channel,due.Unix()),
+			Channel: channel,
+			Payload: entities.OutboxPayload{Type: entities.NotificationDigest, UserId: user.Id.String(), Body: body, Message: &msg},
+		}
+		if err:=ns.OutboxRepository.Enqueue(c,digest);err!=nil{
+			return nil,err
+		}
+		return nil,nil
+	})
+	if err!=nil{
+		return err
+	}
 	return nil
 }
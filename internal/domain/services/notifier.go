@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"crap/internal/domain/repositories"
+	"crap/internal/locales"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Notifier sends an outbox message to a user over one outside channel, e.g.
+// Telegram, email or a webhook. The contact tells it where the user is
+// reached on that channel.
+type Notifier interface {
+	Notify(ctx context.Context, contact entities.Contact, msg entities.OutboxMessage) error
+}
+
+// defaultChannels reach users who have not chosen channels for a type.
+var defaultChannels = []string{entities.ChannelTelegram}
+
+// channelDeliverer adapts a Notifier to the outbox. It looks the recipient
+// and their contact up at delivery time, renders the message in their
+// language and drops it when there is no contact, e.g. the user unlinked
+// Telegram after it was queued. The schedule of the recipient can hold the
+// message for their digest or until their quiet hours end. A reminder or
+// start message that would only arrive once its event has begun is dropped.
+type channelDeliverer struct {
+	Channel           string
+	Notifier          Notifier
+	UserRepository    repositories.UserRepository
+	ChannelRepository repositories.ChannelRepository
+	DigestRepository  repositories.DigestRepository
+}
+
+func NewChannelDeliverer(channel string, n Notifier, ur repositories.UserRepository, cr repositories.ChannelRepository, dr repositories.DigestRepository) Deliverer {
+	return &channelDeliverer{
+		Channel:           channel,
+		Notifier:          n,
+		UserRepository:    ur,
+		ChannelRepository: cr,
+		DigestRepository:  dr,
+	}
+}
+
+func (cd *channelDeliverer) Deliver(ctx context.Context, msg entities.OutboxMessage) error {
+	user, err := cd.UserRepository.FindById(ctx, msg.Payload.UserId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	contact, err := cd.contact(ctx, user)
+	if err != nil {
+		return err
+	}
+	if contact == nil {
+		return nil
+	}
+	schedule, err := cd.ChannelRepository.FetchSchedule(ctx, user.Id.String())
+	if err != nil {
+		return err
+	}
+	if schedule.Digests(msg.Payload.Type) {
+		return cd.DigestRepository.Add(ctx, entities.DigestItem{Id: msg.Id, UserId: user.Id, Channel: cd.Channel, Payload: msg.Payload, CreatedAt: msg.CreatedAt})
+	}
+	if until, held := schedule.Holds(msg.Payload.Type, time.Now(), user.Location()); held {
+		if expires(msg, until) {
+			return nil
+		}
+		return &Postponed{Until: until}
+	}
+	contact.Language = locales.Resolve(user.Language)
+	if msg.Payload.Message != nil {
+		body, err := locales.Render(user.Language, user.Location(), *msg.Payload.Message)
+		if err != nil {
+			return err
+		}
+		msg.Payload.Body = body
+	}
+	return cd.Notifier.Notify(ctx, *contact, msg)
+}
+
+// expires reports whether the message is about the start of an event that
+// is due by t, so delivering it at t would come too late.
+func expires(msg entities.OutboxMessage, t time.Time) bool {
+	if msg.Payload.Type != entities.NotificationReminder && msg.Payload.Type != entities.NotificationStart {
+		return false
+	}
+	if msg.Payload.Message == nil || msg.Payload.Message.Params.Time.IsZero() {
+		return false
+	}
+	return !msg.Payload.Message.Params.Time.After(t)
+}
+
+func (cd *channelDeliverer) contact(ctx context.Context, user *entities.User) (*entities.Contact, error) {
+	if cd.Channel != entities.ChannelTelegram {
+		return cd.ChannelRepository.FindContact(ctx, user.Id.String(), cd.Channel)
+	}
+	if !user.HasTelegram() {
+		return nil, nil
+	}
+	return &entities.Contact{UserId: user.Id, Channel: cd.Channel, Address: user.ChatId}, nil
+}
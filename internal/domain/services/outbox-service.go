@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"crap/config"
+	"crap/internal/domain/entities"
+	"crap/internal/domain/repositories"
+	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotAdmin     = errors.New("admin rights required")
+	ErrNoDeadLetter = errors.New("dead letter not found")
+)
+
+// Deliverer hands an outbox message to one channel. An error schedules
+// another attempt, so a delivery must tolerate being repeated.
+type Deliverer interface {
+	Deliver(ctx context.Context, msg entities.OutboxMessage) error
+}
+
+// Committer is implemented by deliverers with work that must wait until the
+// delivery is committed, e.g. pushing a notification to open streams. A
+// client resumes after the last notification it saw, so that one must
+// already be stored. Its error does not undo the delivery.
+type Committer interface {
+	Committed(ctx context.Context, msg entities.OutboxMessage) error
+}
+
+// Postponed is returned by a deliverer that may not deliver yet, e.g. in the
+// quiet hours of the recipient. The message waits until then without losing
+// an attempt.
+type Postponed struct {
+	Until time.Time
+}
+
+func (p *Postponed) Error() string {
+	return "postponed until " + p.Until.Format(time.RFC3339)
+}
+
+type OutboxService interface {
+	Dispatch(ctx context.Context, now time.Time) (int, error)
+	FetchDead(ctx context.Context, req dto.CursorRequest, callerId string) (*query.Page[entities.OutboxMessage], error)
+	Replay(ctx context.Context, id, callerId string) error
+}
+
+type outboxService struct {
+	OutboxRepository repositories.OutboxRepository
+	Deliverers       map[string]Deliverer
+	Transactor       repositories.Transactor
+	Config           *config.Config
+}
+
+func NewOutboxService(or repositories.OutboxRepository, deliverers map[string]Deliverer, t repositories.Transactor, cfg *config.Config) OutboxService {
+	return &outboxService{
+		OutboxRepository: or,
+		Deliverers:       deliverers,
+		Transactor:       t,
+		Config:           cfg,
+	}
+}
+
+// outboxSettings fills the zero values of the outbox config with defaults.
+func outboxSettings(cfg *config.Config) config.OutboxCfg {
+	s := cfg.Outbox
+	if s.BatchSize <= 0 {
+		s.BatchSize = 50
+	}
+	if s.MaxAttempts <= 0 {
+		s.MaxAttempts = 8
+	}
+	if s.BaseDelay <= 0 {
+		s.BaseDelay = 10 * time.Second
+	}
+	if s.MaxDelay <= 0 {
+		s.MaxDelay = time.Hour
+	}
+	if s.Lease <= 0 {
+		s.Lease = time.Minute
+	}
+	return s
+}
+
+// backoff doubles the delay after every failed attempt up to the maximum.
+func backoff(s config.OutboxCfg, attempts int) time.Duration {
+	delay := s.BaseDelay
+	for i := 1; i < attempts && delay < s.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.MaxDelay)
+}
+
+// Dispatch delivers a batch of due messages and returns how many went
+// through. A message that keeps failing is moved to the dead letters after
+// the configured number of attempts. Such messages and errors of work after
+// a committed delivery are returned together once the batch is done.
+func (ob *outboxService) Dispatch(ctx context.Context, now time.Time) (int, error) {
+	s := outboxSettings(ob.Config)
+	messages, err := ob.OutboxRepository.ClaimDue(ctx, now, now.Add(s.Lease), s.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	failed := []error{}
+	for _, msg := range messages {
+		deliverer, ok := ob.Deliverers[msg.Channel]
+		if !ok {
+			if err := ob.OutboxRepository.MarkDead(ctx, msg.Id.String(), "no deliverer for channel "+msg.Channel); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		_, err := ob.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+			if err := deliverer.Deliver(c, msg); err != nil {
+				return nil, err
+			}
+			if err := ob.OutboxRepository.MarkDelivered(c, msg.Id.String(), time.Now()); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		})
+		if err == nil {
+			if c, ok := deliverer.(Committer); ok {
+				if err := c.Committed(ctx, msg); err != nil {
+					failed = append(failed, err)
+				}
+			}
+			delivered++
+			continue
+		}
+		postponed:=&Postponed{}
+		if errors.As(err,&postponed){
+			if err:=ob.OutboxRepository.Postpone(ctx,msg.Id.String(),postponed.Until);err!=nil{
+				return delivered,err
+			}
+			continue
+		}
+		if msg.Attempts >= s.MaxAttempts {
+			if err := ob.OutboxRepository.MarkDead(ctx, msg.Id.String(), err.Error()); err != nil {
+				return delivered, err
+			}
+			failed = append(failed, fmt.Errorf("outbox message %v is dead after %d attempts: %w", msg.Key, msg.Attempts, err))
+			continue
+		}
+		if err := ob.OutboxRepository.MarkFailed(ctx, msg.Id.String(), err.Error(), now.Add(backoff(s, msg.Attempts))); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, errors.Join(failed...)
+}
+
+func (ob *outboxService) FetchDead(ctx context.Context, req dto.CursorRequest, callerId string) (*query.Page[entities.OutboxMessage], error) {
+	if !ob.isAdmin(callerId) {
+		return nil, ErrNotAdmin
+	}
+	w, err := window(req)
+	if err != nil {
+		return nil, err
+	}
+	return ob.OutboxRepository.FetchDead(ctx, w)
+}
+
+// Replay gives a dead letter a new set of attempts.
+func (ob *outboxService) Replay(ctx context.Context, id, callerId string) error {
+	if !ob.isAdmin(callerId) {
+		return ErrNotAdmin
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNoDeadLetter
+	}
+	replayed, err := ob.OutboxRepository.Replay(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !replayed {
+		return ErrNoDeadLetter
+	}
+	return nil
+}
+
+func (ob *outboxService) isAdmin(id string) bool {
+	return id != "" && slices.Contains(ob.Config.Auth.Admins, id)
+}
@@ -0,0 +1,15 @@
+package services
+
+import (
+	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
+)
+
+// window turns a list request into the part of the list to fetch.
+func window(req dto.CursorRequest) (query.Window, error) {
+	cursor, err := query.ParseCursor(req.Cursor)
+	if err != nil {
+		return query.Window{}, err
+	}
+	return query.Window{Cursor: cursor, Amount: req.Amount, Page: req.Page}, nil
+}
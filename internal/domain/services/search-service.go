@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"crap/internal/domain/repositories"
+	"crap/internal/dto"
+)
+
+type SearchService interface {
+	Search(ctx context.Context, req dto.SearchRequest, callerId string) ([]entities.SearchHit, error)
+}
+
+type searchService struct {
+	SearchRepository repositories.SearchRepository
+}
+
+func NewSearchService(sr repositories.SearchRepository) SearchService {
+	return &searchService{
+		SearchRepository: sr,
+	}
+}
+
+func (ss *searchService) Search(ctx context.Context, req dto.SearchRequest, callerId string) ([]entities.SearchHit, error){
+	hits,err:=ss.SearchRepository.Search(ctx,req.Query,req.Type,callerId,req.Amount,req.Page)
+	if err!=nil{
+		return nil,err
+	}
+	return hits,nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"crap/internal/domain/entities"
+	"crap/internal/domain/repositories"
+	"crap/internal/dto"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type TemplateService interface {
+	SaveTemplate(ctx context.Context, req dto.SaveTemplateRequest, callerId string) (*entities.EventTemplate, error)
+	GetTemplates(ctx context.Context, callerId string) ([]entities.EventTemplate, error)
+	DeleteTemplate(ctx context.Context, id, callerId string) error
+	ShareTemplate(ctx context.Context, id string, req dto.ShareTemplateRequest, callerId string) error
+}
+
+type templateService struct {
+	TemplateRepository    repositories.TemplateRepository
+	EventRepository       repositories.EventRepository
+	GameRepository        repositories.GameRepository
+	FriendshipsRepository repositories.FriendshipsRepository
+	Transactor            repositories.Transactor
+}
+
+func NewTemplateService(
+	tr repositories.TemplateRepository,
+	er repositories.EventRepository,
+	gr repositories.GameRepository,
+	fr repositories.FriendshipsRepository,
+	t repositories.Transactor) TemplateService {
+	return &templateService{
+		TemplateRepository:    tr,
+		EventRepository:       er,
+		GameRepository:        gr,
+		FriendshipsRepository: fr,
+		Transactor:            t,
+	}
+}
+
+// SaveTemplate stores an event of the caller under the given name so it can
+// be created again with only a new start time.
+func (ts *templateService) SaveTemplate(ctx context.Context, req dto.SaveTemplateRequest, callerId string) (*entities.EventTemplate, error){
+	res,err:=ts.Transactor.WithinTransaction(ctx,func(c context.Context) (any, error) {
+		event,err:=ts.EventRepository.FindById(c,req.EventId)
+		if err!=nil{
+			return nil,err
+		}
+		if event.AuthorId.String() != callerId{
+			return nil,errors.New("only the author can save the event as a template")
+		}
+		game,err:=ts.GameRepository.FindByName(c,event.Game)
+		if err!=nil{
+			return nil,err
+		}
+		roles,err:=ts.EventRepository.FetchMemberRoles(c,event.Id.String())
+		if err!=nil{
+			return nil,err
+		}
+		template:=entities.EventTemplate{
+			Id: uuid.New(),
+			AuthorId: event.AuthorId,
+			Name: req.Name,
+			Body: event.Body,
+			Game: game.Id,
+			Max: event.Max,
+			Duration: int(event.EndTime.Sub(event.Time).Minutes()),
+			TimeZone: event.TimeZone,
+			Roles: event.Roles,
+			AuthorRole: roles[callerId],
+			Visibility: event.Visibility,
+			MinReliability: event.MinReliability,
+		}
+		if err:=ts.TemplateRepository.Create(c,template);err!=nil{
+			return nil,err
+		}
+		return &template,nil
+	})
+	if err!=nil{
+		return nil,err
+	}
+	return res.(*entities.EventTemplate),nil
+}
+
+func (ts *templateService) GetTemplates(ctx context.Context, callerId string) ([]entities.EventTemplate, error){
+	templates,err:=ts.TemplateRepository.FetchAvailable(ctx,callerId)
+	if err!=nil{
+		return nil,err
+	}
+	return templates,nil
+}
+
+func (ts *templateService) DeleteTemplate(ctx context.Context, id, callerId string) error{
+	template,err:=ts.TemplateRepository.FindById(ctx,id)
+	if err!=nil{
+		return err
+	}
+	if template.AuthorId.String() != callerId{
+		return errors.New("only the author can delete the template")
+	}
+	if err:=ts.TemplateRepository.Delete(ctx,id);err!=nil{
+		return err
+	}
+	return nil
+}
+
+// ShareTemplate lets a friend of the author create events from the template.
+func (ts *templateService) ShareTemplate(ctx context.Context, id string, req dto.ShareTemplateRequest, callerId string) error{
+	template,err:=ts.TemplateRepository.FindById(ctx,id)
+	if err!=nil{
+		return err
+	}
+	if template.AuthorId.String() != callerId{
+		return errors.New("only the author can share the template")
+	}
+	friends,err:=ts.FriendshipsRepository.AreFriends(ctx,callerId,req.FriendId)
+	if err!=nil{
+		return err
+	}
+	if !friends{
+		return errors.New("templates can be shared with friends only")
+	}
+	if err:=ts.TemplateRepository.Share(ctx,id,req.FriendId);err!=nil{
+		return err
+	}
+	return nil
+}
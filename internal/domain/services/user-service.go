@@ -3,36 +3,70 @@ package services
 import (
 	"context"
 	"crap/config"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
 	"crap/internal/dto"
+	"crap/internal/infrastructure/db/query"
+	"crap/internal/locales"
 	"errors"
 	"fmt"
 	"io"
-	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	ErrTelegramNotConfigured = errors.New("telegram bot is not configured")
 )
 
+// defaultLinkTTL is how long a Telegram link stays valid when the config
+// does not say.
+const defaultLinkTTL = 10 * time.Minute
+
 type UserService interface {
 	GetById(ctx context.Context, id string) (*entities.User, error)
-	Fetch(ctx context.Context, req dto.PaginationRequest) ([]entities.User, error)
+	Fetch(ctx context.Context, req dto.CursorRequest) (*query.Page[entities.User], error)
 	UploadAvatar(ctx context.Context, req dto.UploadAvatarRequest) error
 	DeleteAvatar(ctx context.Context, id string) error
 	RecordDiscord(ctx context.Context, req dto.RecordDiscordRequest) error
-	EditRating(ctx context.Context, req dto.EditRatingRequest) error
+	RecordTimeZone(ctx context.Context, req dto.RecordTimeZoneRequest, callerId string) error
+	RecordLanguage(ctx context.Context, req dto.RecordLanguageRequest, callerId string) error
+	GetReminders(ctx context.Context, id string) ([]int, error)
+	SetReminders(ctx context.Context, req dto.SetRemindersRequest, callerId string) error
+	RateUser(ctx context.Context, req dto.RateUserRequest, callerId string) (*entities.Rating, error)
+	GetRatings(ctx context.Context, req dto.GetRatingsRequest) ([]entities.Rating, error)
+	GetCalendarToken(ctx context.Context, id string) (string, error)
+	ResetCalendarToken(ctx context.Context, id string) (string, error)
+	GetCalendar(ctx context.Context, id, token string) ([]byte, error)
+	LinkTelegram(ctx context.Context, callerId string) (*dto.TelegramLinkResponse, error)
+	UnlinkTelegram(ctx context.Context, callerId string) error
 }
 
 type userService struct {
 	UserRepository repositories.UserRepository
+	EventRepository repositories.EventRepository
+	RatingRepository repositories.RatingRepository
+	ReminderRepository repositories.ReminderRepository
+	LinkRepository repositories.LinkRepository
 	Transactor     repositories.Transactor
 	Config *config.Config
 }
 
-func NewUserService(ur repositories.UserRepository, t repositories.Transactor, cfg *config.Config) UserService {
+func NewUserService(ur repositories.UserRepository, er repositories.EventRepository, rtr repositories.RatingRepository, rr repositories.ReminderRepository, lr repositories.LinkRepository, t repositories.Transactor, cfg *config.Config) UserService {
 	return &userService{
 		UserRepository: ur,
+		EventRepository: er,
+		RatingRepository: rtr,
+		ReminderRepository: rr,
+		LinkRepository: lr,
 		Transactor:     t,
 		Config: cfg,
 	}
@@ -43,11 +77,16 @@ func (us *userService) GetById(ctx context.Context, id string) (*entities.User,
 	if err != nil {
 		return nil, err
 	}
+	user.TelegramLinked = user.HasTelegram()
 	return user, nil
 }
 
-func (us *userService) Fetch(ctx context.Context, req dto.PaginationRequest) ([]entities.User, error) {
-	users, err := us.UserRepository.Fetch(ctx, req.Amount, req.Page)
+func (us *userService) Fetch(ctx context.Context, req dto.CursorRequest) (*query.Page[entities.User], error) {
+	w, err := window(req)
+	if err != nil {
+		return nil, err
+	}
+	users, err := us.UserRepository.Fetch(ctx, w)
 	if err != nil {
 		return nil, err
 	}
@@ -141,15 +180,225 @@ func (us *userService) RecordDiscord(ctx context.Context, req dto.RecordDiscordR
 	return nil
 }
 
-func (us *userService) EditRating(ctx context.Context, req dto.EditRatingRequest) error {
-	user, err := us.GetById(ctx, req.UserId)
+func (us *userService) RecordTimeZone(ctx context.Context, req dto.RecordTimeZoneRequest, callerId string) error {
+	user, err := us.GetById(ctx, callerId)
 	if err != nil {
 		return err
 	}
-	user.NumberOfRatings++
-	user.TotalRating += req.Stars
-	averageRating := float64(user.TotalRating) / float64(user.NumberOfRatings)
-	user.Rating = math.Round(averageRating*2) / 2
+	user.TimeZone = req.TimeZone
+	if err := us.UserRepository.Save(ctx, *user); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RecordLanguage sets the language the user's notifications and bot replies
+// are rendered in.
+func (us *userService) RecordLanguage(ctx context.Context, req dto.RecordLanguageRequest, callerId string) error {
+	if !locales.Supported(req.Language) {
+		return ErrUnsupportedLanguage
+	}
+	user, err := us.GetById(ctx, callerId)
+	if err != nil {
+		return err
+	}
+	user.Language = req.Language
+	if err := us.UserRepository.Save(ctx, *user); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RateUser records the caller's rating of another player. Both of them must
+// have been members of the same finished event, and a player can be rated
+// once per event by each teammate.
+func (us *userService) RateUser(ctx context.Context, req dto.RateUserRequest, callerId string) (*entities.Rating, error) {
+	if req.RateeId == callerId {
+		return nil, errors.New("users cannot rate themselves")
+	}
+	res, err := us.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		rater, err := us.UserRepository.FindById(c, callerId)
+		if err != nil {
+			return nil, err
+		}
+		ratee, err := us.UserRepository.FindById(c, req.RateeId)
+		if err != nil {
+			return nil, err
+		}
+		event, err := us.EventRepository.FindById(c, req.EventId)
+		if err != nil {
+			return nil, err
+		}
+		if event.State != entities.EventFinished {
+			return nil, errors.New("players can be rated only after the event is finished")
+		}
+		for _, id := range []string{rater.Id.String(), ratee.Id.String()} {
+			member, err := us.EventRepository.IsMember(c, id, event.Id.String())
+			if err != nil {
+				return nil, err
+			}
+			if !member {
+				return nil, errors.New("both players must be members of the event")
+			}
+		}
+		rating := entities.Rating{
+			Id:      uuid.New(),
+			RaterId: rater.Id,
+			RateeId: ratee.Id,
+			EventId: event.Id,
+			Stars:   req.Stars,
+			Comment: req.Comment,
+			Time:    time.Now(),
+		}
+		if err := us.RatingRepository.Create(c, rating); err != nil {
+			return nil, err
+		}
+		if err := us.UserRepository.UpdateRating(c, ratee.Id.String()); err != nil {
+			return nil, err
+		}
+		return &rating, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*entities.Rating), nil
+}
+
+func (us *userService) GetRatings(ctx context.Context, req dto.GetRatingsRequest) ([]entities.Rating, error) {
+	user, err := us.UserRepository.FindById(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if req.Direction == "given" {
+		return us.RatingRepository.FetchGiven(ctx, user.Id.String(), req.Amount, req.Page)
+	}
+	return us.RatingRepository.FetchReceived(ctx, user.Id.String(), req.Amount, req.Page)
+}
+
+// GetReminders returns the user's reminder lead times in minutes, or the
+// default one when the user has not chosen any.
+func (us *userService) GetReminders(ctx context.Context, id string) ([]int, error) {
+	user, err := us.UserRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := us.ReminderRepository.FetchOffsets(ctx, user.Id.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(offsets) == 0 {
+		offsets = []int{defaultReminder(us.Config)}
+	}
+	return offsets, nil
+}
+
+func (us *userService) SetReminders(ctx context.Context, req dto.SetRemindersRequest, callerId string) error {
+	_, err := us.Transactor.WithinTransaction(ctx, func(c context.Context) (any, error) {
+		user, err := us.UserRepository.FindById(c, callerId)
+		if err != nil {
+			return nil, err
+		}
+		if err := us.ReminderRepository.SaveOffsets(c, user.Id.String(), req.Offsets); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetCalendarToken returns the secret of the user's calendar feed, creating it
+// on the first request.
+func (us *userService) GetCalendarToken(ctx context.Context, id string) (string, error) {
+	token, err := us.UserRepository.FetchCalendarToken(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		return token, nil
+	}
+	return us.ResetCalendarToken(ctx, id)
+}
+
+// ResetCalendarToken replaces the secret, so the old feed link stops working.
+func (us *userService) ResetCalendarToken(ctx context.Context, id string) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := us.UserRepository.SaveCalendarToken(ctx, id, token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetCalendar renders every event the user joined as an ICS feed. The feed is
+// fetched by calendar apps without a session, so the token is the only check.
+func (us *userService) GetCalendar(ctx context.Context, id, token string) ([]byte, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidCalendarToken
+	}
+	stored, err := us.UserRepository.FetchCalendarToken(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if stored == "" || subtle.ConstantTimeCompare([]byte(stored), []byte(token)) != 1 {
+		return nil, ErrInvalidCalendarToken
+	}
+	user, err := us.UserRepository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := us.EventRepository.FetchJoined(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return renderCalendar(us.Config, user.Login, events), nil
+}
+
+// LinkTelegram issues a one-time code that binds the Telegram chat opening
+// the returned link to the caller. A chat is bound only through such a code,
+// the username saved on the profile proves nothing.
+func (us *userService) LinkTelegram(ctx context.Context, callerId string) (*dto.TelegramLinkResponse, error) {
+	if us.Config.Bot.Name == "" {
+		return nil, ErrTelegramNotConfigured
+	}
+	user, err := us.UserRepository.FindById(ctx, callerId)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	code := hex.EncodeToString(b)
+	ttl := us.Config.Bot.LinkTTL
+	if ttl <= 0 {
+		ttl = defaultLinkTTL
+	}
+	if err := us.LinkRepository.Issue(ctx, code, user.Id.String(), ttl); err != nil {
+		return nil, err
+	}
+	return &dto.TelegramLinkResponse{
+		Url: fmt.Sprintf("https://t.me/%s?start=%s", strings.TrimPrefix(us.Config.Bot.Name, "@"), code),
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+// UnlinkTelegram forgets the chat of the caller, Telegram notifications stop
+// until a new link is opened.
+func (us *userService) UnlinkTelegram(ctx context.Context, callerId string) error {
+	user, err := us.UserRepository.FindById(ctx, callerId)
+	if err != nil {
+		return err
+	}
+	if !user.HasTelegram() {
+		return nil
+	}
+	user.ChatId = ""
 	if err := us.UserRepository.Save(ctx, *user); err != nil {
 		return err
 	}
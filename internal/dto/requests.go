@@ -7,10 +7,21 @@ type PaginationRequest struct {
 	Amount int `query:"amount" validate:"required,gt=0"`
 }
 
+// CursorRequest pages a list by keyset: the first page is requested without a
+// cursor, the next ones with next_cursor or prev_cursor of the last response.
+// Page is still honoured when no cursor is given.
+type CursorRequest struct {
+	Cursor string `query:"cursor"`
+	Page   int    `query:"page" validate:"omitempty,gt=0"`
+	Amount int    `query:"amount" validate:"required,gt=0,lte=100"`
+}
+
 type RegisterRequest struct {
 	Login    string `json:"login" validate:"required,max=100"`
 	Telegram string `json:"telegram" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	TimeZone string `json:"time-zone" validate:"omitempty,timezone"`
+	Language string `json:"language" validate:"omitempty,max=8"`
 }
 
 type LoginRequest struct {
@@ -20,19 +31,87 @@ type LoginRequest struct {
 
 type CreateEventRequest struct {
 	AuthorId string `json:"author-id" validate:"required"`
-	Game     string `json:"game" validate:"required"`
+	TemplateId string `json:"template-id"`
+	Game     string `json:"game" validate:"required_without=TemplateId"`
 	Body     string `json:"body" validate:"max=150"`
-	Max      int    `json:"max" validate:"required"`
-	Minute   int    `json:"minute" validate:"required"`
+	Max      int    `json:"max" validate:"required_without_all=Roles TemplateId,omitempty,gt=0"`
+	StartAt  string `json:"start-at" validate:"required"`
+	TimeZone string `json:"time-zone" validate:"required_without=TemplateId,omitempty,timezone"`
+	Duration int `json:"duration" validate:"omitempty,gt=0,lte=1440"`
+	Recurrence *RecurrenceRequest `json:"recurrence"`
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public friends invite"`
+	Roles    []RoleSlotRequest `json:"roles" validate:"omitempty,dive"`
+	AuthorRole string `json:"author-role" validate:"required_with=Roles,max=45"`
+	MinReliability float64 `json:"min-reliability" validate:"gte=0,lte=1"`
+}
+
+type RoleSlotRequest struct {
+	Name  string `json:"name" validate:"required,max=45"`
+	Slots int    `json:"slots" validate:"required,gt=0"`
+}
+
+type GetEventsRequest struct{
+	State string `query:"state" validate:"omitempty,oneof=scheduled starting in_progress finished cancelled"`
+	CursorRequest
+}
+
+type RecurrenceRequest struct {
+	Freq     string   `json:"freq" validate:"required,oneof=daily weekly"`
+	Interval int      `json:"interval" validate:"omitempty,gt=0"`
+	Weekdays []string `json:"weekdays" validate:"omitempty,dive,oneof=MO TU WE TH FR SA SU"`
+	Until    string   `json:"until"`
+	Count    int      `json:"count" validate:"omitempty,gt=0"`
+}
+
+type EditEventRequest struct{
+	Body     *string `json:"body" validate:"omitempty,max=150"`
+	Game     *string `json:"game" validate:"omitempty,min=1"`
+	Max      *int    `json:"max" validate:"omitempty,gt=0"`
+	StartAt  *string `json:"start-at" validate:"omitempty,min=1"`
+	TimeZone *string `json:"time-zone" validate:"omitempty,timezone"`
+	Duration *int    `json:"duration" validate:"omitempty,gt=0,lte=1440"`
+	Visibility *string `json:"visibility" validate:"omitempty,oneof=public friends invite"`
+	MinReliability *float64 `json:"min-reliability" validate:"omitempty,gte=0,lte=1"`
+}
+
+type CancelEventRequest struct{
+	Reason string `json:"reason" validate:"max=150"`
+}
+
+type SkipOccurrenceRequest struct{
+	EventId string `json:"event-id" validate:"required"`
+	Date string `json:"date" validate:"required"`
+}
+
+type SaveTemplateRequest struct{
+	EventId string `json:"event-id" validate:"required"`
+	Name string `json:"name" validate:"required,max=45"`
+}
+
+type ShareTemplateRequest struct{
+	FriendId string `json:"friend-id" validate:"required"`
+}
+
+type EnqueueLfgRequest struct{
+	Game string `json:"game" validate:"required"`
+	PartySize int `json:"party-size" validate:"required,gte=2,lte=10"`
+	MinRating float64 `json:"min-rating" validate:"gte=0,lte=5"`
+	MaxRating float64 `json:"max-rating" validate:"gte=0,lte=5"`
 }
 
 type JoinToEventRequest struct{
 	UserId string `json:"user-id" validate:"required"`
 	EventId string `json:"event-id" validate:"required"`
+	Role string `json:"role" validate:"max=45"`
 }
 
 type UnjoinFromEventRequest struct{
-	JoinToEventRequest
+	UserId string `json:"user-id" validate:"required"`
+	EventId string `json:"event-id" validate:"required"`
+}
+
+type JoinByInviteRequest struct{
+	Role string `query:"role" validate:"max=45"`
 }
 
 type AddFriendRequest struct{
@@ -51,12 +130,14 @@ type CancelFriendshipRequest struct{
 
 type GetFriendsRequest struct{
 	UserId string `query:"user-id" validate:"required"`
-	PaginationRequest
+	CursorRequest
 }
 
 type GetNotificationsRequest struct{
 	UserId string `query:"user-id" validate:"required"`
-	PaginationRequest
+	Read string `query:"read" validate:"omitempty,oneof=read unread"`
+	EventId string `query:"event-id" validate:"omitempty,uuid"`
+	CursorRequest
 }
 
 type GetFriendsReqRequests struct{
@@ -80,7 +161,7 @@ type AddCommentRequest struct{
 type GetCommentsRequest struct{
 	Whose string `query:"whose" validate:"required,max=5"`
 	Id string `query:"id" validate:"required"`
-	PaginationRequest
+	CursorRequest
 }
 
 type AddGameRequest struct{
@@ -102,31 +183,67 @@ type RecordDiscordRequest struct{
 	Discord string `json:"discord" validate:"required"`
 }
 
-type EditRatingRequest struct{
-	UserId string `json:"user-id" validate:"required"`
+type RecordTimeZoneRequest struct{
+	TimeZone string `json:"time-zone" validate:"required,timezone"`
+}
+
+type RecordLanguageRequest struct{
+	Language string `json:"language" validate:"required,max=8"`
+}
+
+type SetRemindersRequest struct{
+	Offsets []int `json:"offsets" validate:"required,min=1,max=5,dive,gt=0,lte=43200"`
+}
+
+type RateUserRequest struct{
+	RateeId string `json:"ratee-id" validate:"required"`
+	EventId string `json:"event-id" validate:"required"`
 	Stars int `json:"stars" validate:"required,oneof=1 2 3 4 5"`
+	Comment string `json:"comment" validate:"max=300"`
+}
+
+type GetRatingsRequest struct{
+	UserId string `query:"user-id" validate:"required"`
+	Direction string `query:"direction" validate:"required,oneof=given received"`
+	PaginationRequest
+}
+
+type SearchRequest struct{
+	Query string `query:"q" validate:"required,max=100"`
+	Type string `query:"type" validate:"omitempty,oneof=event game news user"`
+	PaginationRequest
 }
 
 type GamesFilterRequest struct{
+	// Name is a comma separated list of game names.
 	Name string `query:"game-name"`
 	PaginationRequest
 }
 
 type EventsFilterRequest struct{
+	// Game is a comma separated list of game names.
 	Game string `query:"game"`
-	Max string `query:"max"`
-	Time string `query:"time"`
+	Max int `query:"max" validate:"omitempty,gt=0"`
+	MinMax int `query:"min-max" validate:"omitempty,gt=0"`
+	Time string `query:"time" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Role string `query:"role"`
 	PaginationRequest
 }
 
+// GamesSortRequest takes comma separated fields (number_of_events,
+// number_of_players, rating) with directions at the same positions.
 type GamesSortRequest struct{
-	Field string `query:"field" validate:"required,oneof=number_of_events number_of_players rating"`
+	Field string `query:"field" validate:"required"`
 	Direction string `query:"direction"`
 	PaginationRequest
 }
 
+// EventsSortRequest takes comma separated fields (max, time) with directions
+// at the same positions.
 type EventsSortRequest struct{
-	Field string `query:"field" validate:"required,oneof=max time"`
+	Field string `query:"field" validate:"required"`
 	Direction string `query:"direction"`
 	PaginationRequest
 }
@@ -134,4 +251,35 @@ type EventsSortRequest struct{
 type DeleteNotificationRequest struct{
 	UserId string `json:"user-id" validate:"required"`
 	NotificationId string `json:"notification-id" validate:"required"`
-}
\ No newline at end of file
+}
+
+// MarkNotificationsReadRequest takes either the ids of the notifications or
+// Before, which marks every notification up to that time.
+type MarkNotificationsReadRequest struct{
+	Ids []string `json:"ids" validate:"required_without=Before,excluded_with=Before,max=100,dive,uuid"`
+	Before string `json:"before" validate:"required_without=Ids,omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type SetChannelPreferenceRequest struct{
+	Type string `json:"type" validate:"required,oneof=reminder start event waitlist lfg friend comment"`
+	Channels []string `json:"channels" validate:"max=3,unique,dive,oneof=telegram email webhook"`
+}
+
+// SetScheduleRequest takes "15:04" times in the zone of the user. Without
+// QuietFrom and QuietTo there are no quiet hours, DigestAt is the time of the
+// daily digest.
+type SetScheduleRequest struct{
+	QuietFrom string `json:"quiet_from" validate:"required_with=QuietTo,omitempty,datetime=15:04"`
+	QuietTo string `json:"quiet_to" validate:"required_with=QuietFrom,omitempty,datetime=15:04"`
+	Digest string `json:"digest" validate:"required,oneof=off hourly daily"`
+	DigestAt string `json:"digest_at" validate:"required_if=Digest daily,omitempty,datetime=15:04"`
+	RemindersInQuiet bool `json:"reminders_in_quiet"`
+}
+
+type SetEmailRequest struct{
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+type SetWebhookRequest struct{
+	Url string `json:"url" validate:"required,url,startswith=https://,max=2048"`
+}
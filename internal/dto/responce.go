@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"crap/internal/domain/entities"
 	"github.com/google/uuid"
 	"time"
 )
@@ -22,3 +23,56 @@ type EventResponse struct {
 	AuthorId uuid.UUID `json:"author-id"`
 	Time     time.Time `json:"time"`
 }
+
+type EventDetailsResponse struct {
+	entities.Event
+	Members          int `json:"members"`
+	FreeSlots        int `json:"free_slots"`
+	WaitlistPosition int `json:"waitlist_position"`
+	Roles            []RoleAvailability `json:"roles,omitempty"`
+}
+
+type RoleAvailability struct {
+	Name  string `json:"name"`
+	Slots int    `json:"slots"`
+	Free  int    `json:"free"`
+}
+
+type LfgStatusResponse struct {
+	Ticket  *entities.LfgTicket `json:"ticket"`
+	Waiting int                 `json:"waiting"`
+}
+
+type RemindersResponse struct {
+	Offsets []int `json:"offsets"`
+}
+
+type JoinEventResponse struct {
+	Status   string `json:"status"`
+	Position int    `json:"position,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+type CalendarLinkResponse struct {
+	Url string `json:"url"`
+}
+
+// TelegramLinkResponse is the link that binds a Telegram chat to the account.
+// It works once and until ExpiresAt.
+type TelegramLinkResponse struct {
+	Url       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type WebhookResponse struct {
+	Url    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+type MarkReadResponse struct {
+	Marked int64 `json:"marked"`
+}
+
+type UnreadResponse struct {
+	Unread int `json:"unread"`
+}
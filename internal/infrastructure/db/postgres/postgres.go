@@ -4,30 +4,68 @@ import (
 	"context"
 	"crap/config"
 	"fmt"
+	"strconv"
 	"time"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const (
+	defaultMaxConns = 10
+	defaultHealthCheckPeriod = time.Minute
+	defaultStatementTimeout = 5*time.Second
+)
 
-
-func Connect(cfg *config.Config) (*pgx.Conn, error){
-	url:=fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&timezone=Europe/Moscow",
+// Connect opens a connection pool, so the handlers, the scheduler and the bot
+// never share a single connection. Zero values in the config fall back to the
+// defaults above.
+func Connect(cfg *config.Config) (*pgxpool.Pool, error){
+	url:=fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&timezone=UTC",
 		cfg.Postgres.User,
 		cfg.Postgres.Password,
 		cfg.Postgres.Host,
 		cfg.Postgres.Port,
 		cfg.Postgres.Database,
 	)
+	poolCfg,err:=pgxpool.ParseConfig(url)
+	if err!=nil{
+		return nil,err
+	}
+	poolCfg.MaxConns=defaultMaxConns
+	if cfg.Postgres.MaxConns>0{
+		poolCfg.MaxConns=cfg.Postgres.MaxConns
+	}
+	if cfg.Postgres.MinConns>0{
+		poolCfg.MinConns=min(cfg.Postgres.MinConns,poolCfg.MaxConns)
+	}
+	poolCfg.HealthCheckPeriod=defaultHealthCheckPeriod
+	if cfg.Postgres.HealthCheckPeriod>0{
+		poolCfg.HealthCheckPeriod=cfg.Postgres.HealthCheckPeriod
+	}
+	if cfg.Postgres.MaxConnLifetime>0{
+		poolCfg.MaxConnLifetime=cfg.Postgres.MaxConnLifetime
+	}
+	if cfg.Postgres.MaxConnIdleTime>0{
+		poolCfg.MaxConnIdleTime=cfg.Postgres.MaxConnIdleTime
+	}
+	timeout:=defaultStatementTimeout
+	if cfg.Postgres.StatementTimeout>0{
+		timeout=cfg.Postgres.StatementTimeout
+	}
+	poolCfg.ConnConfig.RuntimeParams["statement_timeout"]=strconv.FormatInt(timeout.Milliseconds(),10)
 	ctx,cancel:=context.WithTimeout(context.Background(),time.Second*5)
 	defer cancel()
-	conn,err:=pgx.Connect(ctx,url)
+	pool,err:=pgxpool.NewWithConfig(ctx,poolCfg)
 	if err!=nil{
 		return nil,err
 	}
-	return conn,nil
+	if err:=pool.Ping(ctx);err!=nil{
+		pool.Close()
+		return nil,err
+	}
+	return pool,nil
 }
 
-// func(pg *Postgres) CloseConn(stop chan struct{},conn *pgx.Conn){
+// func(pg *Postgres) CloseConn(stop chan struct{},conn *pgxpool.Pool){
 // 	ctx,cancel:=context.WithTimeout(context.Background(),time.Second*5)
 // 	defer cancel()
 // 	<-stop
@@ -0,0 +1,134 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Key is the ordering a keyset page walks: a sort expression with the row id
+// as a tie breaker. The types are the SQL types cursor values are cast to.
+type Key struct {
+	Column string
+	Type   string
+	Id     string
+	IdType string
+	Desc   bool
+}
+
+// Cursor is the position a page continues from. Clients get it encoded and
+// send it back untouched. Key is the column of the ordering it was made for,
+// a cursor of another list is rejected instead of cast to the wrong type.
+type Cursor struct {
+	Key   string `json:"k"`
+	Value string `json:"v"`
+	Id    string `json:"i"`
+	Back  bool   `json:"b,omitempty"`
+}
+
+func (c Cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a cursor from a previous response, an empty string
+// means the first page.
+func ParseCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrInvalid)
+	}
+	cursor := Cursor{}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Id == "" || cursor.Key == "" {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrInvalid)
+	}
+	return &cursor, nil
+}
+
+// Window is the part of a list a caller asks for. Without a cursor it falls
+// back to the page number.
+type Window struct {
+	Cursor *Cursor
+	Amount int
+	Page   int
+}
+
+// Page is a list of results with the cursors of its neighbours.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Keyset orders by the key and limits the result to the window. One row past
+// the window is fetched so Paginate can tell whether more rows follow.
+func (b *Builder) Keyset(key Key, w Window) *Builder {
+	if w.Cursor != nil && w.Cursor.Key != key.Column {
+		b.err = fmt.Errorf("%w: cursor of another list", ErrInvalid)
+		return b
+	}
+	desc := key.Desc
+	if w.Cursor != nil && w.Cursor.Back {
+		desc = !desc
+	}
+	if w.Cursor != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		b.where = append(b.where, fmt.Sprintf("(%s, %s) %s (%s::text::%s, %s::text::%s)",
+			key.Column, key.Id, op, b.Arg(w.Cursor.Value), key.Type, b.Arg(w.Cursor.Id), key.IdType))
+	}
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	b.order = append(b.order, key.Column+dir, key.Id+dir)
+	if w.Cursor != nil || w.Page <= 1 {
+		b.limit = "LIMIT " + b.Arg(w.Amount+1)
+	} else {
+		b.limit = fmt.Sprintf("OFFSET %s LIMIT %s", b.Arg(w.Amount*w.Page-w.Amount), b.Arg(w.Amount+1))
+	}
+	return b
+}
+
+// Paginate turns the rows of a Keyset query by key into a page. position
+// returns the cursor of a row with Value and Id set.
+func Paginate[T any](items []T, key Key, w Window, position func(T) Cursor) Page[T] {
+	more := len(items) > w.Amount
+	if more {
+		items = items[:w.Amount]
+	}
+	back := w.Cursor != nil && w.Cursor.Back
+	if back {
+		slices.Reverse(items)
+	}
+	page := Page[T]{Items: items}
+	if len(items) == 0 {
+		return page
+	}
+	first, last := position(items[0]), position(items[len(items)-1])
+	first.Key, last.Key = key.Column, key.Column
+	first.Back = true
+	if back {
+		page.HasMore = true
+		page.NextCursor = last.String()
+		if more {
+			page.PrevCursor = first.String()
+		}
+		return page
+	}
+	page.HasMore = more
+	if more {
+		page.NextCursor = last.String()
+	}
+	if w.Cursor != nil || w.Page > 1 {
+		page.PrevCursor = first.String()
+	}
+	return page
+}
@@ -0,0 +1,131 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testKey = Key{Column: "e.time", Type: "timestamptz", Id: "e.id", IdType: "uuid"}
+
+func TestKeyset(t *testing.T) {
+	tests := []struct {
+		name string
+		key  Key
+		w    Window
+		want string
+		args []any
+	}{
+		{"first page", testKey, Window{Amount: 10, Page: 1},
+			"SELECT * FROM events e ORDER BY e.time ASC, e.id ASC LIMIT $1", []any{11}},
+		{"page number", testKey, Window{Amount: 10, Page: 3},
+			"SELECT * FROM events e ORDER BY e.time ASC, e.id ASC OFFSET $1 LIMIT $2", []any{20, 11}},
+		{"next", testKey, Window{Amount: 10, Cursor: &Cursor{Key: "e.time", Value: "v", Id: "i"}},
+			"SELECT * FROM events e WHERE (e.time, e.id) > ($1::text::timestamptz, $2::text::uuid) ORDER BY e.time ASC, e.id ASC LIMIT $3", []any{"v", "i", 11}},
+		{"back", testKey, Window{Amount: 10, Cursor: &Cursor{Key: "e.time", Value: "v", Id: "i", Back: true}},
+			"SELECT * FROM events e WHERE (e.time, e.id) < ($1::text::timestamptz, $2::text::uuid) ORDER BY e.time DESC, e.id DESC LIMIT $3", []any{"v", "i", 11}},
+		{"descending next", Key{Column: "e.time", Type: "timestamptz", Id: "e.id", IdType: "uuid", Desc: true}, Window{Amount: 10, Cursor: &Cursor{Key: "e.time", Value: "v", Id: "i"}},
+			"SELECT * FROM events e WHERE (e.time, e.id) < ($1::text::timestamptz, $2::text::uuid) ORDER BY e.time DESC, e.id DESC LIMIT $3", []any{"v", "i", 11}},
+	}
+	for _, tt := range tests {
+		sql, args, err := Select("*", "events e", testColumns).Keyset(tt.key, tt.w).Build()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if sql != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, sql, tt.want)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: got args %v, want %v", tt.name, args, tt.args)
+		}
+	}
+}
+
+func TestKeysetBindsCursorValues(t *testing.T) {
+	cursor, err := ParseCursor(Cursor{Key: testKey.Column, Value: "2025-01-01'; DROP TABLE events; --", Id: "x' OR '1'='1"}.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql, args, err := Select("*", "events e", testColumns).Keyset(testKey, Window{Amount: 5, Cursor: cursor}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sql, "DROP") || strings.Contains(sql, "'") {
+		t.Errorf("cursor reached the statement: %q", sql)
+	}
+	if args[0] != cursor.Value || args[1] != cursor.Id {
+		t.Errorf("got args %v", args)
+	}
+}
+
+func TestKeysetRejectsCursorOfAnotherKey(t *testing.T) {
+	cursor := &Cursor{Key: "coalesce(rating,0)", Value: "4.5", Id: "i"}
+	_, _, err := Select("*", "events e", testColumns).Keyset(testKey, Window{Amount: 5, Cursor: cursor}).Build()
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("got %v, want ErrInvalid", err)
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	c := Cursor{Key: "k", Value: "v", Id: "i", Back: true}
+	got, err := ParseCursor(c.String())
+	if err != nil || *got != c {
+		t.Errorf("round trip: got %v, %v", got, err)
+	}
+	if got, err := ParseCursor(""); got != nil || err != nil {
+		t.Errorf("empty: got %v, %v", got, err)
+	}
+	for _, s := range []string{"not base64!", Cursor{Key: "k", Value: "v"}.String(), Cursor{Value: "v", Id: "i"}.String(), "e30"} {
+		if _, err := ParseCursor(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("ParseCursor(%q): got %v, want ErrInvalid", s, err)
+		}
+	}
+}
+
+func position(n int) Cursor {
+	return Cursor{Value: string(rune('a' + n)), Id: string(rune('a' + n))}
+}
+
+// keyed is the cursor Paginate hands out for the row.
+func keyed(n int) Cursor {
+	c := position(n)
+	c.Key = testKey.Column
+	return c
+}
+
+func TestPaginate(t *testing.T) {
+	rows := []int{0, 1, 2, 3}
+
+	first := Paginate(rows, testKey, Window{Amount: 3, Page: 1}, position)
+	if !reflect.DeepEqual(first.Items, []int{0, 1, 2}) || !first.HasMore || first.PrevCursor != "" {
+		t.Errorf("first page: %+v", first)
+	}
+	next, _ := ParseCursor(first.NextCursor)
+	if *next != keyed(2) {
+		t.Errorf("first page next cursor: %+v", next)
+	}
+
+	last := Paginate([]int{3}, testKey, Window{Amount: 3, Cursor: next}, position)
+	if last.HasMore || last.NextCursor != "" || last.PrevCursor == "" {
+		t.Errorf("last page: %+v", last)
+	}
+	prev, _ := ParseCursor(last.PrevCursor)
+	if !prev.Back || prev.Id != position(3).Id {
+		t.Errorf("last page prev cursor: %+v", prev)
+	}
+
+	// going back the rows come in reverse order and are put straight
+	back := Paginate([]int{2, 1, 0}, testKey, Window{Amount: 2, Cursor: prev}, position)
+	if !reflect.DeepEqual(back.Items, []int{1, 2}) || !back.HasMore || back.PrevCursor == "" {
+		t.Errorf("back page: %+v", back)
+	}
+	if c, _ := ParseCursor(back.NextCursor); c.Back || c.Id != position(2).Id {
+		t.Errorf("back page next cursor: %+v", c)
+	}
+
+	empty := Paginate([]int{}, testKey, Window{Amount: 3, Page: 1}, position)
+	if len(empty.Items) != 0 || empty.HasMore || empty.NextCursor != "" {
+		t.Errorf("empty page: %+v", empty)
+	}
+}
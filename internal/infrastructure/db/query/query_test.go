@@ -0,0 +1,115 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testColumns = Columns{"game": "e.game", "time": "e.time", "max": "e.max"}
+
+// injections are caller inputs that would change the statement if they
+// reached the SQL text.
+var injections = []string{
+	"game; DROP TABLE events",
+	"e.game",
+	"game--",
+	"1=1",
+	`game" OR "1"="1`,
+	"",
+}
+
+func TestUnknownColumnsAreRejected(t *testing.T) {
+	builders := map[string]func(b *Builder, field string){
+		"Eq":      func(b *Builder, f string) { b.Eq(f, "x") },
+		"Gte":     func(b *Builder, f string) { b.Gte(f, 1) },
+		"Lte":     func(b *Builder, f string) { b.Lte(f, 1) },
+		"Between": func(b *Builder, f string) { b.Between(f, 1, 2) },
+		"In":      func(b *Builder, f string) { b.In(f, []string{"a"}) },
+		"OrderBy": func(b *Builder, f string) { b.OrderBy(Order{Field: f}) },
+	}
+	for name, build := range builders {
+		for _, field := range injections {
+			b := Select("*", "events e", testColumns)
+			build(b, field)
+			sql, args, err := b.Build()
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("%s(%q): want ErrInvalid, got %q %v %v", name, field, sql, args, err)
+			}
+			if sql != "" {
+				t.Errorf("%s(%q): want no statement with an error, got %q", name, field, sql)
+			}
+		}
+	}
+}
+
+func TestUnknownDirectionsAreRejected(t *testing.T) {
+	for _, dir := range []string{"asc; DROP TABLE events", "DESC NULLS FIRST", "up", "desc,asc", "1"} {
+		sql, _, err := Select("*", "events e", testColumns).OrderBy(Order{Field: "game", Dir: dir}).Build()
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("direction %q: want ErrInvalid, got %q %v", dir, sql, err)
+		}
+	}
+}
+
+func TestDirections(t *testing.T) {
+	tests := map[string]string{"": "ASC", "asc": "ASC", "ASC": "ASC", "desc": "DESC", "Desc": "DESC"}
+	for dir, want := range tests {
+		sql, _, err := Select("*", "events e", testColumns).OrderBy(Order{Field: "game", Dir: dir}).Build()
+		if err != nil {
+			t.Fatalf("direction %q: %v", dir, err)
+		}
+		if !strings.HasSuffix(sql, "ORDER BY e.game "+want) {
+			t.Errorf("direction %q: got %q", dir, sql)
+		}
+	}
+}
+
+func TestValuesAreBound(t *testing.T) {
+	value := "x' OR '1'='1'; DROP TABLE events; --"
+	sql, args, err := Select("*", "events e", testColumns).
+		Eq("game", value).
+		In("game", []string{value}).
+		Between("time", value, value).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sql, "DROP") || strings.Contains(sql, "'") {
+		t.Errorf("value reached the statement: %q", sql)
+	}
+	want := "SELECT * FROM events e WHERE e.game = $1 AND e.game = ANY($2) AND e.time BETWEEN $3 AND $4"
+	if sql != want {
+		t.Errorf("got %q, want %q", sql, want)
+	}
+	if !reflect.DeepEqual(args, []any{value, []string{value}, value, value}) {
+		t.Errorf("got args %v", args)
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	tests := []struct {
+		fields, dirs string
+		want         []Order
+	}{
+		{"game", "", []Order{{Field: "game"}}},
+		{"game,time", "desc,asc", []Order{{"game", "desc"}, {"time", "asc"}}},
+		{"game, time ,max", "desc", []Order{{"game", "desc"}, {"time", ""}, {"max", ""}}},
+		{"game,,time", "desc,desc,desc", []Order{{"game", "desc"}, {"time", "desc"}}},
+		{"", "desc", []Order{}},
+	}
+	for _, tt := range tests {
+		got := ParseOrder(tt.fields, tt.dirs)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseOrder(%q, %q) = %v, want %v", tt.fields, tt.dirs, got, tt.want)
+		}
+	}
+	sql, _, err := Select("*", "events e", testColumns).OrderBy(ParseOrder("game,time", "desc,desc")...).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(sql, "ORDER BY e.game DESC, e.time DESC") {
+		t.Errorf("got %q", sql)
+	}
+}
@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crap/config"
+	"crap/internal/domain/entities"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email sends notifications over SMTP. STARTTLS and authentication are used
+// when the server offers them, so a plain local server works as well.
+type Email struct {
+	Addr    string
+	From    *mail.Address
+	Auth    smtp.Auth
+	AppName string
+}
+
+// NewEmail returns nil when no SMTP host is configured.
+func NewEmail(cfg *config.Config) (*Email, error) {
+	if cfg.Smtp.Host == "" {
+		return nil, nil
+	}
+	from, err := mail.ParseAddress(cfg.Smtp.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp sender: %w", err)
+	}
+	e := &Email{
+		Addr:    net.JoinHostPort(cfg.Smtp.Host, cfg.Smtp.Port),
+		From:    from,
+		AppName: cfg.App.Name,
+	}
+	if cfg.Smtp.User != "" {
+		e.Auth = smtp.PlainAuth("", cfg.Smtp.User, cfg.Smtp.Password, cfg.Smtp.Host)
+	}
+	return e, nil
+}
+
+func (e *Email) Notify(ctx context.Context, contact entities.Contact, msg entities.OutboxMessage) error {
+	host, _, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return err
+	}
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(e.Auth); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.Mail(e.From.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(contact.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(e.message(contact.Address, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message renders a plain text mail. The body is base64 encoded, so the
+// Russian texts pass servers without 8BITMIME.
+func (e *Email) message(to string, msg entities.OutboxMessage) []byte {
+	subject, _, _ := strings.Cut(msg.Payload.Body, "\n")
+	if e.AppName != "" {
+		subject = e.AppName + ": " + subject
+	}
+	_, domain, _ := strings.Cut(e.From.Address, "@")
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", e.From.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", msg.Id, domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Payload.Body))
+	for len(body) > 76 {
+		b.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	b.WriteString(body + "\r\n")
+	return b.Bytes()
+}
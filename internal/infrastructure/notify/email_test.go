@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crap/internal/domain/entities"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one session and records the commands and the data.
+type fakeSMTP struct {
+	addr     string
+	commands chan []string
+	data     chan string
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeSMTP{addr: ln.Addr().String(), commands: make(chan []string, 1), data: make(chan string, 1)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		commands := []string{}
+		defer func() { f.commands <- commands }()
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			commands = append(commands, line)
+			verb, _, _ := strings.Cut(strings.ToUpper(line), " ")
+			switch verb {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				lines, err := tp.ReadDotLines()
+				if err != nil {
+					return
+				}
+				f.data <- strings.Join(lines, "\r\n")
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return f
+}
+
+func TestEmailNotify(t *testing.T) {
+	f := startFakeSMTP(t)
+	e := &Email{
+		Addr:    f.addr,
+		From:    &mail.Address{Name: "crap", Address: "bot@crap.test"},
+		AppName: "crap",
+	}
+	body := "Рейд (WoW) начнется через 10 мин, в 1 июня 2025 15:00 MSK!\nhttp://localhost:3000/events/c0ffee00-0000-0000-0000-000000000000"
+	msg := testMessage(body)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Notify(ctx, entities.Contact{Channel: entities.ChannelEmail, Address: "user@example.com"}, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	var data string
+	select {
+	case data = <-f.data:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no DATA received")
+	}
+	commands := <-f.commands
+	if !slices.Contains(commands, "MAIL FROM:<bot@crap.test>") {
+		t.Errorf("no MAIL FROM in %q", commands)
+	}
+	if !slices.Contains(commands, "RCPT TO:<user@example.com>") {
+		t.Errorf("no RCPT TO in %q", commands)
+	}
+	if commands[len(commands)-1] != "QUIT" {
+		t.Errorf("session did not end with QUIT: %q", commands)
+	}
+
+	header, encoded, ok := strings.Cut(data, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("no header separator in %q", data)
+	}
+	m, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(header + "\r\n\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Header.Get("To"); got != "user@example.com" {
+		t.Errorf("got To %q", got)
+	}
+	if got := m.Header.Get("Content-Transfer-Encoding"); got != "base64" {
+		t.Errorf("got transfer encoding %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "crap: Рейд (WoW) начнется через 10 мин, в 1 июня 2025 15:00 MSK!" {
+		t.Errorf("got subject %q, %v", subject, err)
+	}
+	for _, line := range strings.Split(encoded, "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("body line longer than 76: %q", line)
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\r\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != body {
+		t.Errorf("got body %q, want %q", decoded, body)
+	}
+}
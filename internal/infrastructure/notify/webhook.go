@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crap/config"
+	"crap/internal/domain/entities"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	IdHeader        = "X-Webhook-Id"
+)
+
+// ErrPrivateAddress is returned for webhook urls that resolve to an address
+// outside of the public internet.
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, netip does not count it
+// as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Webhook posts notifications as JSON to the user's url. Every request is
+// signed with the user's secret, see Sign.
+type Webhook struct {
+	Client *http.Client
+}
+
+// NewWebhook returns a sender that only reaches public addresses. The urls
+// come from users, so loopback, private and link-local hosts, e.g. the cloud
+// metadata endpoint, are refused. The check runs on the address being dialed,
+// after the name is resolved, and redirects are not followed.
+func NewWebhook(cfg *config.Config) *Webhook {
+	timeout := cfg.Webhook.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout, Control: publicOnly}
+	return &Webhook{
+		Client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// publicOnly is a dialer control that refuses non-public addresses.
+func publicOnly(network, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !public(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+func public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() &&
+		!ip.IsPrivate() &&
+		!ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+type webhookPayload struct {
+	Id      string    `json:"id"`
+	Type    string    `json:"type"`
+	EventId string    `json:"event_id"`
+	Body    string    `json:"body"`
+	Time    time.Time `json:"time"`
+}
+
+// Sign returns the hex HMAC-SHA256 of the timestamp and the body joined by a
+// dot. Receivers recompute it and compare it with the signature header, and
+// reject old timestamps to stop replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhook) Notify(ctx context.Context, contact entities.Contact, msg entities.OutboxMessage) error {
+	body, err := json.Marshal(webhookPayload{
+		Id:      msg.Id.String(),
+		Type:    msg.Payload.Type,
+		EventId: msg.Payload.EventId.String(),
+		Body:    msg.Payload.Body,
+		Time:    msg.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, contact.Address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdHeader, msg.Id.String())
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(contact.Secret, timestamp, body))
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
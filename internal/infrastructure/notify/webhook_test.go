@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"context"
+	"crap/config"
+	"crap/internal/domain/entities"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testMessage(body string) entities.OutboxMessage {
+	return entities.OutboxMessage{
+		Id:        uuid.New(),
+		Channel:   entities.ChannelWebhook,
+		Payload:   entities.OutboxPayload{Type: entities.NotificationReminder, EventId: uuid.New(), Body: body},
+		CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	msg := testMessage("Рейд начнется через 10 мин")
+	contact := entities.Contact{Channel: entities.ChannelWebhook, Address: srv.URL, Secret: "secret"}
+	if err := (&Webhook{Client: srv.Client()}).Notify(context.Background(), contact, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := webhookPayload{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	want := webhookPayload{Id: msg.Id.String(), Type: msg.Payload.Type, EventId: msg.Payload.EventId.String(), Body: msg.Payload.Body, Time: msg.CreatedAt}
+	if payload != want {
+		t.Errorf("got payload %+v, want %+v", payload, want)
+	}
+	if got := header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("got content type %q", got)
+	}
+	if got := header.Get(IdHeader); got != msg.Id.String() {
+		t.Errorf("got id header %q", got)
+	}
+	timestamp := header.Get(TimestampHeader)
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Errorf("got timestamp header %q", timestamp)
+	}
+
+	// the receiver side, computed without Sign
+	mac := hmac.New(sha256.New, []byte(contact.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := header.Get(SignatureHeader); !hmac.Equal([]byte(got), []byte(expected)) {
+		t.Errorf("got signature %q, want %q", got, expected)
+	}
+	if Sign("other", timestamp, body) == Sign(contact.Secret, timestamp, body) {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestWebhookNotifyFailsOnErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	contact := entities.Contact{Address: srv.URL, Secret: "secret"}
+	if err := (&Webhook{Client: srv.Client()}).Notify(context.Background(), contact, testMessage("x")); err == nil {
+		t.Error("want an error for 502")
+	}
+}
+
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	reached := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer srv.Close()
+	contact := entities.Contact{Address: srv.URL, Secret: "secret"}
+	err := NewWebhook(&config.Config{}).Notify(context.Background(), contact, testMessage("x"))
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("want ErrPrivateAddress, got %v", err)
+	}
+	if reached {
+		t.Error("the loopback server was reached")
+	}
+}
+
+func TestPublic(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+		"::ffff:10.0.0.1": false,
+	}
+	for ip, want := range tests {
+		if got := public(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("public(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package locales renders the texts users get, notifications and bot
+// replies, from per-language catalogs. A catalog is a JSON file named after
+// its language with text/template messages, see ru.json for the placeholders.
+package locales
+
+import (
+	"bytes"
+	"crap/internal/domain/entities"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default is the language of users who have not picked one. Its catalog
+// also fills in messages another catalog is missing.
+const Default = "ru"
+
+// Message keys.
+const (
+	Reminder         = "notification.reminder"
+	Start            = "notification.start"
+	EventChanged     = "notification.event_changed"
+	EventCancelled   = "notification.event_cancelled"
+	WaitlistPromoted = "notification.waitlist_promoted"
+	LfgMatched       = "notification.lfg_matched"
+	FriendRequest    = "notification.friend_request"
+	ProfileComment   = "notification.profile_comment"
+	EventComment     = "notification.event_comment"
+	Digest           = "notification.digest"
+
+	BotAsk               = "bot.ask"
+	BotSubscribeYes      = "bot.subscribe_yes"
+	BotSubscribeNo       = "bot.subscribe_no"
+	BotLinked            = "bot.linked"
+	BotLinkInvalid       = "bot.link_invalid"
+	BotLinkRequired      = "bot.link_required"
+	BotAlreadySubscribed = "bot.already_subscribed"
+	BotUnsubscribed      = "bot.unsubscribed"
+	BotNotSubscribed     = "bot.not_subscribed"
+	BotCheckInButton     = "bot.check_in_button"
+	BotCheckedIn         = "bot.checked_in"
+	BotCheckInFailed     = "bot.check_in_failed"
+
+	leadDays      = "lead.days"
+	leadHours     = "lead.hours"
+	leadMinutes   = "lead.minutes"
+	changePrefix  = "change."
+	listSeparator = "list_separator"
+)
+
+//go:embed *.json
+var files embed.FS
+
+type catalog struct {
+	// TimeLayout formats the start of an event.
+	TimeLayout string            `json:"time_layout"`
+	Messages   map[string]string `json:"messages"`
+	templates  map[string]*template.Template
+}
+
+var catalogs = mustLoad()
+
+func mustLoad() map[string]*catalog {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		panic(err)
+	}
+	catalogs := map[string]*catalog{}
+	for _, entry := range entries {
+		data, err := files.ReadFile(entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		c := catalog{}
+		if err := json.Unmarshal(data, &c); err != nil {
+			panic(fmt.Sprintf("locale %s: %v", entry.Name(), err))
+		}
+		c.templates = make(map[string]*template.Template, len(c.Messages))
+		for key, text := range c.Messages {
+			t, err := template.New(key).Parse(text)
+			if err != nil {
+				panic(fmt.Sprintf("locale %s: %v", entry.Name(), err))
+			}
+			c.templates[key] = t
+		}
+		if err := c.check(); err != nil {
+			panic(fmt.Sprintf("locale %s: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))] = &c
+	}
+	if catalogs[Default] == nil {
+		panic("no catalog for the default language " + Default)
+	}
+	return catalogs
+}
+
+// Languages lists the languages with a catalog.
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+func Supported(lang string) bool {
+	return catalogs[lang] != nil
+}
+
+// Resolve returns lang when it has a catalog and the default otherwise.
+func Resolve(lang string) string {
+	if Supported(lang) {
+		return lang
+	}
+	return Default
+}
+
+// view is what the templates see. Values that depend on the language or the
+// zone of the reader are rendered before the template runs.
+type view struct {
+	Title          string
+	Game           string
+	Start          string
+	Link           string
+	Lead           string
+	Max            int
+	MinReliability string
+	Reason         string
+	Changes        string
+	Error          string
+	Login          string
+	Count          int
+	Items          []string
+}
+
+// check runs every template on the data it is given, so a placeholder that
+// does not exist stops the app when it starts instead of a message later.
+func (c *catalog) check() error {
+	for key, t := range c.templates {
+		var data any = view{}
+		if strings.HasPrefix(key, "lead.") {
+			data = 1
+		}
+		if err := t.Execute(io.Discard, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Render returns the message in the language, with times in the zone. An
+// unknown key is returned as is, so a missing text shows up but breaks
+// nothing.
+func Render(lang string, loc *time.Location, msg entities.Message) (string, error) {
+	c := catalogs[Resolve(lang)]
+	p := msg.Params
+	v := view{
+		Title:          p.Title,
+		Game:           p.Game,
+		Link:           p.Link,
+		Max:            p.Max,
+		MinReliability: fmt.Sprintf("%.2f", p.MinReliability),
+		Reason:         p.Reason,
+		Error:          p.Error,
+		Login:          p.Login,
+		Count:          p.Count,
+		Items:          p.Items,
+	}
+	if !p.Time.IsZero() {
+		if loc == nil {
+			loc = time.UTC
+		}
+		v.Start = p.Time.In(loc).Format(c.TimeLayout)
+	}
+	if p.Lead > 0 {
+		lead, err := c.lead(p.Lead)
+		if err != nil {
+			return "", err
+		}
+		v.Lead = lead
+	}
+	if len(p.Changes) > 0 {
+		changes := make([]string, 0, len(p.Changes))
+		for _, field := range p.Changes {
+			change, err := c.execute(changePrefix+field, v)
+			if err != nil {
+				return "", err
+			}
+			changes = append(changes, change)
+		}
+		v.Changes = strings.Join(changes, Text(lang, listSeparator))
+	}
+	return c.execute(msg.Key, v)
+}
+
+// Text returns a message without placeholders, e.g. a button label. The
+// catalogs are checked when they are loaded, so it cannot fail.
+func Text(lang, key string) string {
+	text, _ := catalogs[Resolve(lang)].execute(key, view{})
+	return text
+}
+
+// Matches reports whether text is the message in any language. The bot uses
+// it to recognise its own reply buttons.
+func Matches(text, key string) bool {
+	for lang := range catalogs {
+		if Text(lang, key) == text {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *catalog) execute(key string, data any) (string, error) {
+	t, ok := c.templates[key]
+	if !ok {
+		t, ok = catalogs[Default].templates[key]
+	}
+	if !ok {
+		return key, nil
+	}
+	b := bytes.Buffer{}
+	if err := t.Execute(&b, data); err != nil {
+		return key, fmt.Errorf("cannot render message %v: %w", key, err)
+	}
+	return b.String(), nil
+}
+
+// lead renders the time left before an event in whole days, hours or minutes.
+func (c *catalog) lead(d time.Duration) (string, error) {
+	d = d.Round(time.Minute)
+	switch {
+	case d >= 24*time.Hour:
+		return c.execute(leadDays, int(d.Hours()/24))
+	case d >= time.Hour:
+		return c.execute(leadHours, int(d.Hours()))
+	default:
+		return c.execute(leadMinutes, max(int(d.Minutes()), 1))
+	}
+}
@@ -0,0 +1,78 @@
+package locales
+
+import (
+	"crap/internal/domain/entities"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testParams = entities.MessageParams{
+	Title:          "Friday raid",
+	Game:           "dota",
+	Time:           time.Date(2024, 5, 10, 18, 30, 0, 0, time.UTC),
+	Link:           "https://example.com/events/1",
+	Lead:           90 * time.Minute,
+	Max:            5,
+	MinReliability: 0.75,
+	Reason:         "nobody came",
+	Changes:        []string{entities.ChangeBody, entities.ChangeGame, entities.ChangeMax, entities.ChangeMinReliability, entities.ChangeTime},
+	Error:          "event is full",
+	Login:          "player",
+	Count:          2,
+	Items:          []string{"first", "second"},
+}
+
+func TestCatalogsHaveTheSameKeys(t *testing.T) {
+	keys := func(lang string) []string {
+		keys := make([]string, 0, len(catalogs[lang].Messages))
+		for key := range catalogs[lang].Messages {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		return keys
+	}
+	want := keys(Default)
+	for _, lang := range Languages() {
+		if got := keys(lang); !slices.Equal(got, want) {
+			t.Errorf("%s: keys %v, want the keys of %s %v", lang, got, Default, want)
+		}
+	}
+}
+
+func TestRenderEveryKey(t *testing.T) {
+	for _, lang := range Languages() {
+		c := catalogs[lang]
+		for key := range c.Messages {
+			var got string
+			var err error
+			switch {
+			case strings.HasPrefix(key, "lead."):
+				got, err = c.execute(key, 3)
+			case strings.HasPrefix(key, changePrefix), key == listSeparator:
+				got, err = c.execute(key, view{})
+			default:
+				got, err = Render(lang, time.UTC, entities.Message{Key: key, Params: testParams})
+			}
+			if err != nil {
+				t.Errorf("%s %s: %v", lang, key, err)
+				continue
+			}
+			if got == "" || got == key || strings.Contains(got, "<no value>") {
+				t.Errorf("%s %s: rendered %q", lang, key, got)
+			}
+		}
+	}
+}
+
+func TestRenderLead(t *testing.T) {
+	for _, lang := range Languages() {
+		for _, lead := range []time.Duration{30 * time.Second, 45 * time.Minute, 3 * time.Hour, 50 * time.Hour} {
+			msg := entities.Message{Key: Reminder, Params: entities.MessageParams{Title: "t", Lead: lead}}
+			if _, err := Render(lang, time.UTC, msg); err != nil {
+				t.Errorf("%s %v: %v", lang, lead, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN recurrence JSONB;
+ALTER TABLE events ADD COLUMN series_id UUID;
+ALTER TABLE events ADD COLUMN occurrence INT NOT NULL DEFAULT 1;
+UPDATE events SET series_id = id;
+ALTER TABLE events ALTER COLUMN series_id SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN occurrence;
+ALTER TABLE events DROP COLUMN series_id;
+ALTER TABLE events DROP COLUMN recurrence;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE events_waitlist(
+    event_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    seq BIGSERIAL NOT NULL,
+    time TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (event_id,user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE
+)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE events_waitlist
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN state VARCHAR(16) NOT NULL DEFAULT 'scheduled';
+ALTER TABLE events ADD COLUMN end_time TIMESTAMPTZ;
+UPDATE events SET end_time = time + INTERVAL '2 hours';
+ALTER TABLE events ALTER COLUMN end_time SET NOT NULL;
+CREATE INDEX events_state_time_idx ON events (state,time);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX events_state_time_idx;
+ALTER TABLE events DROP COLUMN end_time;
+ALTER TABLE events DROP COLUMN state;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE friendships ADD COLUMN IF NOT EXISTS relation VARCHAR(16) NOT NULL DEFAULT 'requested';
+ALTER TABLE events ADD COLUMN visibility VARCHAR(16) NOT NULL DEFAULT 'public';
+ALTER TABLE events ADD COLUMN invite_code VARCHAR(32) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX events_invite_code_idx ON events (invite_code) WHERE invite_code <> '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX events_invite_code_idx;
+ALTER TABLE events DROP COLUMN invite_code;
+ALTER TABLE events DROP COLUMN visibility;
+-- +goose StatementEnd
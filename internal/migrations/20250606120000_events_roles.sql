@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN roles JSONB;
+ALTER TABLE users_events ADD COLUMN role VARCHAR(45) NOT NULL DEFAULT '';
+ALTER TABLE events_waitlist ADD COLUMN role VARCHAR(45) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events_waitlist DROP COLUMN role;
+ALTER TABLE users_events DROP COLUMN role;
+ALTER TABLE events DROP COLUMN roles;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE reminder_preferences(
+    user_id UUID NOT NULL,
+    offset_minutes INT NOT NULL CHECK (offset_minutes > 0),
+    PRIMARY KEY (user_id,offset_minutes),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE sent_reminders(
+    user_id UUID NOT NULL,
+    event_id UUID NOT NULL,
+    offset_minutes INT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id,event_id,offset_minutes),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE
+);
+INSERT INTO sent_reminders (user_id,event_id,offset_minutes)
+    SELECT ue.user_id, ue.event_id, 10 FROM users_events ue JOIN events e ON e.id = ue.event_id WHERE e.notificated_pre;
+ALTER TABLE events DROP COLUMN notificated_pre;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN notificated_pre BOOLEAN NOT NULL DEFAULT false;
+UPDATE events SET notificated_pre = true WHERE id IN (SELECT event_id FROM sent_reminders);
+DROP TABLE sent_reminders;
+DROP TABLE reminder_preferences;
+-- +goose StatementEnd
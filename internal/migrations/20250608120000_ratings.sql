@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE ratings(
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    rater_id UUID NOT NULL,
+    ratee_id UUID NOT NULL,
+    event_id UUID NOT NULL,
+    stars SMALLINT NOT NULL CHECK (stars BETWEEN 1 AND 5),
+    comment TEXT NOT NULL DEFAULT '',
+    time TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (rater_id,ratee_id,event_id),
+    CHECK (rater_id <> ratee_id),
+    FOREIGN KEY (rater_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (ratee_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE
+);
+CREATE INDEX ratings_ratee_idx ON ratings (ratee_id,time);
+-- ratings given before were not backed by any shared event
+UPDATE users SET rating = 0, total_rating = 0, number_of_ratings = 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE ratings;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users_events ADD COLUMN checked_in_at TIMESTAMPTZ;
+ALTER TABLE users_events ADD COLUMN no_show BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE events ADD COLUMN attendance_checked BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE events ADD COLUMN min_reliability NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN reliability NUMERIC NOT NULL DEFAULT 1;
+-- nobody could check in before, so past events are not counted
+UPDATE events SET attendance_checked = true WHERE state NOT IN ('scheduled','starting');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN reliability;
+ALTER TABLE events DROP COLUMN min_reliability;
+ALTER TABLE events DROP COLUMN attendance_checked;
+ALTER TABLE users_events DROP COLUMN no_show;
+ALTER TABLE users_events DROP COLUMN checked_in_at;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE event_templates(
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    author_id UUID NOT NULL,
+    name VARCHAR(45) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    game VARCHAR(45) NOT NULL,
+    max INT NOT NULL,
+    duration INT NOT NULL,
+    time_zone VARCHAR(64) NOT NULL,
+    roles JSONB,
+    author_role VARCHAR(45) NOT NULL DEFAULT '',
+    visibility VARCHAR(16) NOT NULL DEFAULT 'public',
+    min_reliability NUMERIC NOT NULL DEFAULT 0,
+    UNIQUE (author_id,name),
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE event_templates_shares(
+    template_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    PRIMARY KEY (template_id,user_id),
+    FOREIGN KEY (template_id) REFERENCES event_templates(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE event_templates_shares;
+DROP TABLE event_templates;
+-- +goose StatementEnd
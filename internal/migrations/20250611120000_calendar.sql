@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN calendar_token VARCHAR(64) UNIQUE;
+ALTER TABLE events ADD COLUMN sequence INT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE events DROP COLUMN sequence;
+ALTER TABLE users DROP COLUMN calendar_token;
+-- +goose StatementEnd
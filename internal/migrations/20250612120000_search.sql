@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE events ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(game,'')), 'A') ||
+    setweight(to_tsvector('english', coalesce(game,'')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(body,'')), 'B') ||
+    setweight(to_tsvector('english', coalesce(body,'')), 'B')
+) STORED;
+ALTER TABLE games ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(name,'')), 'A') ||
+    setweight(to_tsvector('english', coalesce(name,'')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description,'')), 'B') ||
+    setweight(to_tsvector('english', coalesce(description,'')), 'B')
+) STORED;
+ALTER TABLE news ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(title,'')), 'A') ||
+    setweight(to_tsvector('english', coalesce(title,'')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(body,'')), 'B') ||
+    setweight(to_tsvector('english', coalesce(body,'')), 'B')
+) STORED;
+ALTER TABLE users ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', coalesce(login,''))
+) STORED;
+CREATE INDEX events_search_idx ON events USING GIN (search);
+CREATE INDEX games_search_idx ON games USING GIN (search);
+CREATE INDEX news_search_idx ON news USING GIN (search);
+CREATE INDEX users_search_idx ON users USING GIN (search);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX users_search_idx;
+DROP INDEX news_search_idx;
+DROP INDEX games_search_idx;
+DROP INDEX events_search_idx;
+ALTER TABLE users DROP COLUMN search;
+ALTER TABLE news DROP COLUMN search;
+ALTER TABLE games DROP COLUMN search;
+ALTER TABLE events DROP COLUMN search;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE notification_preferences(
+    user_id UUID NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    channels TEXT[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (user_id,type),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE notification_contacts(
+    user_id UUID NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    address TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (user_id,channel),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE notification_contacts;
+DROP TABLE notification_preferences;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users_notifications ADD COLUMN read_at TIMESTAMPTZ;
+CREATE INDEX users_notifications_unread_idx ON users_notifications (user_id) WHERE read_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX users_notifications_unread_idx;
+ALTER TABLE users_notifications DROP COLUMN read_at;
+-- +goose StatementEnd
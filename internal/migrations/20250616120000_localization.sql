@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN language VARCHAR(8) NOT NULL DEFAULT 'ru';
+ALTER TABLE notifications ADD COLUMN message JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notifications DROP COLUMN message;
+ALTER TABLE users DROP COLUMN language;
+-- +goose StatementEnd
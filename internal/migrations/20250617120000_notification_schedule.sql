@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE notification_schedules(
+    user_id UUID PRIMARY KEY NOT NULL,
+    quiet_from VARCHAR(5) NOT NULL DEFAULT '',
+    quiet_to VARCHAR(5) NOT NULL DEFAULT '',
+    digest VARCHAR(10) NOT NULL DEFAULT 'off',
+    digest_at VARCHAR(5) NOT NULL DEFAULT '',
+    reminders_in_quiet BOOLEAN NOT NULL DEFAULT false,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE notification_digests(
+    id UUID PRIMARY KEY NOT NULL,
+    user_id UUID NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX notification_digests_user_idx ON notification_digests (user_id,channel);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE notification_digests;
+DROP TABLE notification_schedules;
+-- +goose StatementEnd
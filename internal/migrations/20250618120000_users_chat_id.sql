@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE users SET chat_id = '' WHERE chat_id IS NULL OR chat_id = 'unknown';
+ALTER TABLE users ALTER COLUMN chat_id SET DEFAULT '';
+ALTER TABLE users ALTER COLUMN chat_id SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users ALTER COLUMN chat_id DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN chat_id SET DEFAULT 'unknown';
+UPDATE users SET chat_id = 'unknown' WHERE chat_id = '';
+-- +goose StatementEnd
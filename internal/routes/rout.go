@@ -16,6 +16,10 @@ type RoutConfig struct {
 	NoticeHandler      *handlers.NotificationsHandler
 	FriendshipsHandler *handlers.FriendshipsHandler
 	CommentsHandler    *handlers.CommentsHandler
+	LfgHandler         *handlers.LfgHandler
+	TemplateHandler    *handlers.TemplatesHandler
+	SearchHandler      *handlers.SearchHandler
+	OutboxHandler      *handlers.OutboxHandler
 }
 
 func (rcfg *RoutConfig) Setup() {
@@ -27,20 +31,34 @@ func (rcfg *RoutConfig) Setup() {
 	rcfg.SetupNotificationsRoute()
 	rcfg.SetupFriendshipsRoute()
 	rcfg.SetupCommentRoute()
+	rcfg.SetupLfgRoute()
+	rcfg.SetupSearchRoute()
+	rcfg.SetupAdminRoute()
 	// rcfg.SetupSwaggerConfig()
 }
 
 func (rcfg *RoutConfig) SetupUserRoute() {
     userGroup := rcfg.App.Group("/api/users")
 
+    userGroup.Get("/reminders", rcfg.UserHandler.GetReminders)
+    userGroup.Get("/ratings", rcfg.UserHandler.GetRatings)
+    userGroup.Get("/calendar", rcfg.UserHandler.GetCalendarLink)
+    userGroup.Get("/:id/calendar.ics", rcfg.UserHandler.GetCalendar)
     userGroup.Get("/:id", rcfg.UserHandler.GetUser)
     userGroup.Get("", rcfg.UserHandler.GetUsers)
 
     userGroup.Patch("/avatar", rcfg.UserHandler.UploadAvatar)
     userGroup.Patch("/discord", rcfg.UserHandler.RecordDiscord)
-    userGroup.Patch("/rating", rcfg.UserHandler.EditRating)
+    userGroup.Patch("/timezone", rcfg.UserHandler.RecordTimeZone)
+    userGroup.Patch("/language", rcfg.UserHandler.RecordLanguage)
+    userGroup.Patch("/reminders", rcfg.UserHandler.SetReminders)
+
+    userGroup.Post("/ratings", rcfg.UserHandler.RateUser)
+    userGroup.Post("/calendar", rcfg.UserHandler.ResetCalendarLink)
+    userGroup.Post("/telegram", rcfg.UserHandler.LinkTelegram)
 
     userGroup.Delete("/avatar/:id", rcfg.UserHandler.DeleteAvatar)
+    userGroup.Delete("/telegram", rcfg.UserHandler.UnlinkTelegram)
 }
 
 func (rcfg *RoutConfig) SetupFriendshipsRoute() {
@@ -62,6 +80,27 @@ func (rcfg *RoutConfig) SetupCommentRoute() {
     commentGroup.Post("", rcfg.CommentsHandler.AddComment)
 }
 
+func (rcfg *RoutConfig) SetupLfgRoute() {
+    lfgGroup := rcfg.App.Group("/api/lfg")
+
+    lfgGroup.Get("", rcfg.LfgHandler.Status)
+    lfgGroup.Post("", rcfg.LfgHandler.Enqueue)
+    lfgGroup.Delete("", rcfg.LfgHandler.Leave)
+}
+
+func (rcfg *RoutConfig) SetupSearchRoute() {
+    searchGroup := rcfg.App.Group("/api/search")
+
+    searchGroup.Get("", rcfg.SearchHandler.Search)
+}
+
+func (rcfg *RoutConfig) SetupAdminRoute() {
+    adminGroup := rcfg.App.Group("/api/admin")
+
+    adminGroup.Get("/outbox/dead", rcfg.OutboxHandler.GetDeadLetters)
+    adminGroup.Post("/outbox/:id/replay", rcfg.OutboxHandler.ReplayDeadLetter)
+}
+
 func (rcfg *RoutConfig) SetupAuthRoute() {
     authGroup := rcfg.App.Group("/api/auth")
 
@@ -90,13 +129,23 @@ func (rcfg *RoutConfig) SetupEventRoute() {
 
     eventsGroup.Get("/sort", rcfg.EventHandler.GetSortedEvents)
     eventsGroup.Get("/filter", rcfg.EventHandler.GetFilteredEvents)
+    eventsGroup.Get("/templates", rcfg.TemplateHandler.GetTemplates)
     eventsGroup.Get("/:id", rcfg.EventHandler.GetEvent)
     eventsGroup.Get("", rcfg.EventHandler.GetEvents)
 
     eventsGroup.Patch("/join", rcfg.EventHandler.Join)
+    eventsGroup.Patch("/join/:code", rcfg.EventHandler.JoinByInvite)
     eventsGroup.Patch("/unjoin", rcfg.EventHandler.Unjoin)
+    eventsGroup.Patch("/skip", rcfg.EventHandler.SkipOccurrence)
+    eventsGroup.Patch("/:id", rcfg.EventHandler.EditEvent)
 
+    eventsGroup.Post("/templates", rcfg.TemplateHandler.SaveTemplate)
+    eventsGroup.Post("/templates/:id/share", rcfg.TemplateHandler.ShareTemplate)
+    eventsGroup.Post("/:id/cancel", rcfg.EventHandler.CancelEvent)
+    eventsGroup.Post("/:id/checkin", rcfg.EventHandler.CheckIn)
     eventsGroup.Post("", rcfg.EventHandler.CreateEvent)
+
+    eventsGroup.Delete("/templates/:id", rcfg.TemplateHandler.DeleteTemplate)
 }
 
 func (rcfg *RoutConfig) SetupNewsRoute() {
@@ -111,9 +160,21 @@ func (rcfg *RoutConfig) SetupNewsRoute() {
 func (cfg *RoutConfig) SetupNotificationsRoute() {
     notificationsGroup := cfg.App.Group("/api/notifications")
 
+    notificationsGroup.Get("/stream/ws", cfg.NoticeHandler.StreamSocket)
+    notificationsGroup.Get("/stream", cfg.NoticeHandler.Stream)
+    notificationsGroup.Get("/unread", cfg.NoticeHandler.GetUnread)
+    notificationsGroup.Get("/preferences", cfg.NoticeHandler.GetPreferences)
+    notificationsGroup.Get("/schedule", cfg.NoticeHandler.GetSchedule)
     notificationsGroup.Get("", cfg.NoticeHandler.GetNotifications)
 
+    notificationsGroup.Patch("/read", cfg.NoticeHandler.MarkRead)
+    notificationsGroup.Patch("/preferences", cfg.NoticeHandler.SetPreference)
+    notificationsGroup.Patch("/schedule", cfg.NoticeHandler.SetSchedule)
+    notificationsGroup.Patch("/channels/email", cfg.NoticeHandler.SetEmail)
+    notificationsGroup.Patch("/channels/webhook", cfg.NoticeHandler.SetWebhook)
+
     notificationsGroup.Delete("/all/:id", cfg.NoticeHandler.DeleteAllNotifications) 
+    notificationsGroup.Delete("/channels/:channel", cfg.NoticeHandler.DeleteContact)
     notificationsGroup.Delete("", cfg.NoticeHandler.DeleteNotification)
 }
 
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crap/internal/domain/entities"
 	"crap/internal/domain/repositories"
+	"crap/internal/locales"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,42 +14,65 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// CheckInService records attendance when a member presses the check-in button.
+type CheckInService interface {
+	CheckIn(ctx context.Context, id, callerId string) error
+}
+
+const checkInPrefix = "checkin:"
+
 type Bot struct {
 	bot             *tgbotapi.BotAPI
 	Logger          *logrus.Logger
 	UserRepository  repositories.UserRepository
 	EventRepository repositories.EventRepository
+	Links           repositories.LinkRepository
+	CheckIns        CheckInService
 }
 
-func CreateBot(stop chan struct{}, l *logrus.Logger, userRepository repositories.UserRepository, eventRepository repositories.EventRepository, token string) (*Bot, error) {
+func CreateBot(stop chan struct{}, l *logrus.Logger, userRepository repositories.UserRepository, eventRepository repositories.EventRepository, links repositories.LinkRepository, token string) (*Bot, error) {
 	var err error
 	bot, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
 	}
-	Bot := Bot{bot: bot, UserRepository: userRepository, EventRepository: eventRepository, Logger: l}
+	Bot := Bot{bot: bot, UserRepository: userRepository, EventRepository: eventRepository, Links: links, Logger: l}
 	return &Bot, err
 }
 
-func (b *Bot) SendMsg(event entities.Event, msg string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancel()
-	members, err := b.EventRepository.FetchMembers(ctx, event.Id.String())
+func checkInKeyboard(lang, eventId string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(locales.Text(lang, locales.BotCheckInButton), checkInPrefix+eventId),
+		),
+	)
+}
+
+// language picks the catalog for a reply: the user's choice, or the
+// language of their Telegram client when they are not known yet.
+func language(user *entities.User, from *tgbotapi.User) string {
+	if user != nil && user.Language != "" {
+		return user.Language
+	}
+	if from != nil {
+		return locales.Resolve(from.LanguageCode)
+	}
+	return locales.Default
+}
+
+// Notify is the Telegram Notifier. Errors of the Telegram API are returned,
+// so the outbox sends the message again later.
+func (b *Bot) Notify(ctx context.Context, contact entities.Contact, msg entities.OutboxMessage) error {
+	chatID, err := strconv.ParseInt(contact.Address, 10, 64)
 	if err != nil {
 		return err
 	}
-	for _, id := range members {
-		user, err := b.UserRepository.FindById(context.Background(), id)
-		if err != nil {
-			return err
-		}
-		if user.ChatId != "" {
-			chatID, _ := strconv.ParseInt(user.ChatId, 10, 64)
-			message := tgbotapi.NewMessage(chatID, msg)
-			if _, err := b.bot.Send(message); err != nil {
-				b.Logger.Infof("failed to send message to user %s: %v", user.Telegram, err)
-			}
-		}
+	message := tgbotapi.NewMessage(chatID, msg.Payload.Body)
+	if msg.Payload.CheckIn {
+		message.ReplyMarkup = checkInKeyboard(contact.Language, msg.Payload.EventId.String())
+	}
+	if _, err := b.bot.Send(message); err != nil {
+		return err
 	}
 	return nil
 }
@@ -65,73 +90,140 @@ func (b *Bot) ListenForUpdates(stop chan struct{}) {
 			if update.Message != nil {
 				b.handleMessage(update)
 			}
+			if update.CallbackQuery != nil {
+				b.handleCallback(update)
+			}
 		}
 	}
 
 }
 
 func (b *Bot) handleMessage(update tgbotapi.Update) {
-	username := update.Message.From.UserName
+	if !update.Message.Chat.IsPrivate() {
+		return
+	}
 	chatID := update.Message.Chat.ID
 	text := update.Message.Text
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
-	user, err := b.UserRepository.FindBy(ctx, "telegram", username)
+	if update.Message.Command() == "start" && update.Message.CommandArguments() != "" {
+		b.link(ctx, update.Message, update.Message.CommandArguments())
+		return
+	}
+	user, err := b.UserRepository.FindBy(ctx, "chat_id", strconv.FormatInt(chatID, 10))
 	if err != nil {
 		b.Logger.WithError(err).Info("user not found")
+		user = nil
+	}
+	lang := language(user, update.Message.From)
+
+	if user == nil {
+		answer := locales.Text(lang, locales.BotLinkRequired)
+		if locales.Matches(text, locales.BotSubscribeNo) {
+			answer = locales.Text(lang, locales.BotNotSubscribed)
+		}
+		b.reply(chatID, answer, tgbotapi.NewRemoveKeyboard(true))
+		return
 	}
 
 	keyboard := tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("✅ Да, хочу"),
-			tgbotapi.NewKeyboardButton("❌ Нет, не хочу"),
+			tgbotapi.NewKeyboardButton(locales.Text(lang, locales.BotSubscribeYes)),
+			tgbotapi.NewKeyboardButton(locales.Text(lang, locales.BotSubscribeNo)),
 		),
 	)
 
-	switch text {
-	case "✅ Да, хочу":
-		if user.ChatId == "" {
-			if err := b.storeChatID(user, chatID); err != nil {
-				b.Logger.WithError(err).Info("failed to store chatID")
-			}
-			msg := tgbotapi.NewMessage(chatID, "Теперь вы будете получать уведомления от crap о начале ивентов!")
-			msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-			if _, err := b.bot.Send(msg); err != nil {
-				b.Logger.WithError(err).Info("failed to send msg")
-			}
-		} else {
-			msg := tgbotapi.NewMessage(chatID, "Вы уже подписаны на уведомления.")
-			msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-			if _, err := b.bot.Send(msg); err != nil {
-				b.Logger.WithError(err).Info("failed to send msg")
-			}
-		}
+	switch {
+	case locales.Matches(text, locales.BotSubscribeYes):
+		b.reply(chatID, locales.Text(lang, locales.BotAlreadySubscribed), tgbotapi.NewRemoveKeyboard(true))
 
-	case "❌ Нет, не хочу":
-		if user.ChatId != "" {
-			if err := b.removeChatID(user); err != nil {
-				b.Logger.WithError(err).Info("failed to remove chatID")
-			}
-			msg := tgbotapi.NewMessage(chatID, "Вы отписались от уведомлений.")
-			msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-			if _, err := b.bot.Send(msg); err != nil {
-				b.Logger.WithError(err).Info("failed to send msg")
-			}
-		} else {
-			msg := tgbotapi.NewMessage(chatID, "Вы не подписаны на уведомления.")
-			msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-			if _, err := b.bot.Send(msg); err != nil {
-				b.Logger.WithError(err).Info("failed to send msg")
-			}
+	case locales.Matches(text, locales.BotSubscribeNo):
+		if err := b.removeChatID(user); err != nil {
+			b.Logger.WithError(err).Info("failed to remove chatID")
+			return
 		}
+		b.reply(chatID, locales.Text(lang, locales.BotUnsubscribed), tgbotapi.NewRemoveKeyboard(true))
 
 	default:
-		msg := tgbotapi.NewMessage(chatID, "Хотите ли вы получать уведомления о начале ивентов, к которым вы присоединились?")
-		msg.ReplyMarkup = keyboard
-		if _, err := b.bot.Send(msg); err != nil {
-			b.Logger.WithError(err).Info("failed to send msg")
+		b.reply(chatID, locales.Text(lang, locales.BotAsk), keyboard)
+	}
+}
+
+// link binds the chat to the account the code was issued for. The code is
+// the only proof of the account, a chat bound to another one is moved.
+func (b *Bot) link(ctx context.Context, message *tgbotapi.Message, code string) {
+	chatID := message.Chat.ID
+	id, err := b.Links.Redeem(ctx, code)
+	if err != nil {
+		b.Logger.WithError(err).Info("failed to redeem link code")
+	}
+	if id == "" {
+		b.reply(chatID, locales.Text(language(nil, message.From), locales.BotLinkInvalid), tgbotapi.NewRemoveKeyboard(true))
+		return
+	}
+	user, err := b.UserRepository.FindById(ctx, id)
+	if err != nil {
+		b.Logger.WithError(err).Info("user not found")
+		b.reply(chatID, locales.Text(language(nil, message.From), locales.BotLinkInvalid), tgbotapi.NewRemoveKeyboard(true))
+		return
+	}
+	lang := language(user, message.From)
+	previous, err := b.UserRepository.FindBy(ctx, "chat_id", strconv.FormatInt(chatID, 10))
+	if err == nil && previous.Id != user.Id {
+		if err := b.removeChatID(previous); err != nil {
+			b.Logger.WithError(err).Info("failed to remove chatID")
+			return
 		}
 	}
+	if err := b.storeChatID(user, chatID); err != nil {
+		b.Logger.WithError(err).Info("failed to store chatID")
+		return
+	}
+	b.Logger.Infof("telegram chat linked to user %v", user.Id)
+	answer, err := locales.Render(lang, user.Location(), entities.Message{Key: locales.BotLinked, Params: entities.MessageParams{Login: user.Login}})
+	if err != nil {
+		b.Logger.WithError(err).Error("failed to render the linked message")
+		return
+	}
+	b.reply(chatID, answer, tgbotapi.NewRemoveKeyboard(true))
+}
+
+func (b *Bot) reply(chatID int64, text string, markup any) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = markup
+	if _, err := b.bot.Send(msg); err != nil {
+		b.Logger.WithError(err).Info("failed to send msg")
+	}
+}
+
+func (b *Bot) handleCallback(update tgbotapi.Update) {
+	query := update.CallbackQuery
+	id, ok := strings.CutPrefix(query.Data, checkInPrefix)
+	if !ok || b.CheckIns == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	user, err := b.UserRepository.FindBy(ctx, "chat_id", strconv.FormatInt(query.From.ID, 10))
+	if err != nil {
+		b.Logger.WithError(err).Info("user not found")
+		user = nil
+	}
+	lang := language(user, query.From)
+	answer := locales.Text(lang, locales.BotCheckedIn)
+	if user == nil {
+		answer = locales.Text(lang, locales.BotNotSubscribed)
+	} else if err := b.CheckIns.CheckIn(ctx, id, user.Id.String()); err != nil {
+		failed, rerr := locales.Render(lang, user.Location(), entities.Message{Key: locales.BotCheckInFailed, Params: entities.MessageParams{Error: err.Error()}})
+		if rerr != nil {
+			b.Logger.WithError(rerr).Error("failed to render the check-in failure")
+			failed = err.Error()
+		}
+		answer = failed
+	}
+	if _, err := b.bot.Request(tgbotapi.NewCallback(query.ID, answer)); err != nil {
+		b.Logger.WithError(err).Info("failed to answer callback")
+	}
 }
 
 func (b *Bot) storeChatID(user *entities.User, chatID int64) error {
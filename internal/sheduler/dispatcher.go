@@ -0,0 +1,45 @@
+package sheduler
+
+import (
+	"context"
+	"crap/config"
+	"crap/internal/domain/services"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Dispatcher drains the outbox. It polls more often than the cron job, so a
+// notification reaches the users a few seconds after it was written.
+type Dispatcher struct {
+	OutboxService services.OutboxService
+	Logger        *logrus.Logger
+	Config        *config.Config
+}
+
+func (d *Dispatcher) Run(stop chan struct{}) {
+	interval := d.Config.Outbox.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	d.Logger.Info("starting outbox dispatcher")
+	for {
+		select {
+		case <-stop:
+			d.Logger.Info("outbox dispatcher stopped")
+			return
+		case now := <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+			delivered, err := d.OutboxService.Dispatch(ctx, now)
+			cancel()
+			if err != nil {
+				d.Logger.WithError(err).Errorf("failed to dispatch outbox: %v", err)
+			}
+			if delivered > 0 {
+				d.Logger.Infof("доставлено сообщений из очереди: %v", delivered)
+			}
+		}
+	}
+}
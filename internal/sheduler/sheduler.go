@@ -2,8 +2,9 @@ package sheduler
 
 import (
 	"context"
+	"crap/config"
+	"crap/internal/domain/entities"
 	"crap/internal/domain/services"
-	"crap/internal/sheduler/bot"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -14,40 +15,41 @@ type Sheduler struct {
 	NotificationService services.NotificationService
 	EventService        services.EventService
 	UserService         services.UserService
+	LfgService          services.LfgService
 	Logger              *logrus.Logger
-	Bot                 *bot.Bot
+	Config              *config.Config
 }
 
 func (s *Sheduler) SetupSheduler(stop chan struct{}) {
-	if s.Bot != nil {
-		s.Logger.Info("starting sheduller with bot")
-	} else {
-		s.Logger.Info("bot is nil, shedule whithout bot")
-	}
+	s.Logger.Info("starting sheduller")
 	cr := cron.New()
 	if _, err := cr.AddFunc("@every 1m", func() {
 		now := time.Now()
 		ctx1, cancel := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel()
-		upcoming, err := s.EventService.FindUpcoming(ctx1, now.Add(10*time.Minute).Add(30*time.Second))
+		reminders, err := s.NotificationService.FetchDueReminders(ctx1, now)
+		if err != nil {
+			s.Logger.WithError(err).Errorf("failed to fetch due reminders: %v", err)
+		}
+		for _, reminder := range reminders {
+			sent, err := s.NotificationService.SendReminder(ctx1, reminder, now)
+			if err != nil {
+				s.Logger.WithError(err).Errorf("failed to queue reminder: %v", err)
+				continue
+			}
+			if !sent {
+				continue
+			}
+			s.Logger.Infof("напоминание о событии %v отправлено пользователю %v", reminder.Event.Body, reminder.UserId)
+		}
+		upcoming, err := s.EventService.FindUpcoming(ctx1, now.Add(s.Config.Event.StartingLead))
 		if err != nil {
 			s.Logger.WithError(err).Errorf("failed to fetch upcoming events: %v", err)
 		}
 		for _, event := range upcoming {
-			if !event.NotificatedPre {
-				premsg := "cобытие " + event.Body + " начнется через 10 минут!"
-				if err := s.NotificationService.CreateNotification(ctx1, event, premsg); err != nil {
-					s.Logger.WithError(err).Errorf("failed to create notification: %v", err)
-				}
-				if s.Bot != nil {
-					if err := s.Bot.SendMsg(event, premsg); err != nil {
-						s.Logger.WithError(err).Errorf("error to send message to bot: %v", err)
-					}
-				}
-				s.Logger.Infof("уведомление о предстоящем событии %v отправлено в %v", event.Body, time.Now())
-				event.NotificatedPre = true
-				if err := s.EventService.Save(context.Background(), event); err != nil {
-					s.Logger.WithError(err).Errorf("failed to save event: %v", err)
+			if event.State == entities.EventScheduled {
+				if err := s.EventService.Transition(ctx1, event, entities.EventStarting); err != nil {
+					s.Logger.WithError(err).Errorf("failed to move event %v to starting: %v", event.Id, err)
 				}
 			}
 		}
@@ -58,20 +60,52 @@ func (s *Sheduler) SetupSheduler(stop chan struct{}) {
 			s.Logger.WithError(err).Errorf("failed to fetch upcoming events: %v", err)
 		}
 		for _, event := range current {
-			curmsg := "cобытие " + event.Body + " началось!"
-			if err := s.NotificationService.CreateNotification(ctx2, event, curmsg); err != nil {
-				s.Logger.WithError(err).Errorf("failed to create notification: %v", err)
-			}
-			if s.Bot != nil {
-				if err := s.Bot.SendMsg(event, curmsg); err != nil {
-					s.Logger.WithError(err).Errorf("error to send message to bot: %v", err)
-				}
+			next, err := s.EventService.StartEvent(ctx2, event)
+			if err != nil {
+				s.Logger.WithError(err).Errorf("failed to move event %v to in progress: %v", event.Id, err)
+				continue
 			}
 			s.Logger.Infof("уведомление о начале события %v отправлено в %v", event.Body, time.Now())
-			if err := s.EventService.DeleteEvent(context.Background(), event.Id.String()); err != nil {
-				s.Logger.WithError(err).Errorf("failed to delete event: %v", err)
+			if next != nil {
+				s.Logger.Infof("следующее событие серии %v запланировано на %v", event.SeriesId, next.Time)
 			}
 		}
+		ctx3, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		ended, err := s.EventService.FindEnded(ctx3, now)
+		if err != nil {
+			s.Logger.WithError(err).Errorf("failed to fetch ended events: %v", err)
+		}
+		for _, event := range ended {
+			if err := s.EventService.Transition(ctx3, event, entities.EventFinished); err != nil {
+				s.Logger.WithError(err).Errorf("failed to finish event %v: %v", event.Id, err)
+				continue
+			}
+			s.Logger.Infof("событие %v завершено и перенесено в архив", event.Body)
+		}
+		closed, err := s.EventService.CloseAttendance(ctx3, now)
+		if err != nil {
+			s.Logger.WithError(err).Errorf("failed to close attendance: %v", err)
+		} else if closed > 0 {
+			s.Logger.Infof("посещаемость закрыта, обновлена надежность %v участников", closed)
+		}
+		ctx4, cancel := context.WithTimeout(context.Background(), time.Second*15)
+		defer cancel()
+		matches, err := s.LfgService.Match(ctx4, now)
+		if err != nil {
+			s.Logger.WithError(err).Errorf("failed to match lfg queue: %v", err)
+		}
+		for _, event := range matches {
+			s.Logger.Infof("группа для %v собрана, событие %v", event.Game, event.Id)
+		}
+		ctx5, cancel := context.WithTimeout(context.Background(), time.Second*15)
+		defer cancel()
+		digests, err := s.NotificationService.SendDigests(ctx5, now)
+		if err != nil {
+			s.Logger.WithError(err).Errorf("failed to send digests: %v", err)
+		} else if digests > 0 {
+			s.Logger.Infof("отправлено сводок уведомлений: %v", digests)
+		}
 	}); err != nil {
 		s.Logger.WithError(err).Error("failed to add cron job")
 		return
@@ -0,0 +1,93 @@
+package ical
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+const dateFormat = "20060102T150405Z"
+
+type Calendar struct {
+	ProdId string
+	Name   string
+	Events []Event
+}
+
+type Event struct {
+	Uid         string
+	Sequence    int
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Url         string
+	Status      string
+}
+
+// Marshal renders the calendar as RFC 5545 text, times are written in UTC.
+func (c Calendar) Marshal() []byte {
+	var b bytes.Buffer
+	line(&b, "BEGIN:VCALENDAR")
+	line(&b, "VERSION:2.0")
+	line(&b, "PRODID:"+c.ProdId)
+	line(&b, "CALSCALE:GREGORIAN")
+	line(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		line(&b, "X-WR-CALNAME:"+escape(c.Name))
+	}
+	for _, e := range c.Events {
+		line(&b, "BEGIN:VEVENT")
+		line(&b, "UID:"+e.Uid)
+		line(&b, "SEQUENCE:"+strconv.Itoa(e.Sequence))
+		line(&b, "DTSTAMP:"+e.Stamp.UTC().Format(dateFormat))
+		line(&b, "DTSTART:"+e.Start.UTC().Format(dateFormat))
+		if !e.End.IsZero() {
+			line(&b, "DTEND:"+e.End.UTC().Format(dateFormat))
+		}
+		line(&b, "SUMMARY:"+escape(e.Summary))
+		if e.Description != "" {
+			line(&b, "DESCRIPTION:"+escape(e.Description))
+		}
+		if e.Url != "" {
+			line(&b, "URL:"+e.Url)
+		}
+		if e.Status != "" {
+			line(&b, "STATUS:"+e.Status)
+		}
+		line(&b, "END:VEVENT")
+	}
+	line(&b, "END:VCALENDAR")
+	return b.Bytes()
+}
+
+// line writes a content line folded to 75 octets without splitting runes,
+// the leading space of a continuation counts towards the limit.
+func line(b *bytes.Buffer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}